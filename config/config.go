@@ -55,6 +55,8 @@ type ServiceConfig struct {
 	PaletteConfig   *PaletteConfig
 	BoltDbPath      string
 	RoutineNum      int64
+	QueueLength     int
+	DrainTimeout    int
 	TargetContracts TargetContracts
 }
 
@@ -139,14 +141,40 @@ func (c *ServiceConfig) OpenPolyWallet(polySdk *sdk.PolySdk) (signer *sdk.Accoun
 }
 
 type PaletteConfig struct {
-	SideChainId         uint64
-	RestURL             string
-	ECCMContractAddress string
-	ECCDContractAddress string
-	KeyStorePath        string
-	KeyStorePwdSet      map[string]string
-	BlockConfig         uint64
-	HeadersPerBatch     int
+	SideChainId          uint64
+	RestURL              string
+	ECCMContractAddress  string
+	ECCDContractAddress  string
+	KeyStorePath         string
+	KeyStorePwdSet       map[string]string
+	BlockConfig          uint64
+	HeadersPerBatch      int
+	PrefetchWindow       int
+	FilterBlockRange     int
+	HeaderCommitTimeout  int
+	RetryInterval        int
+	MaxRetryInterval     int
+	MaxRetryAttempts     int
+	CheckBatchSize       int
+	CheckTimeout         int
+	GasPriceStrategy     string
+	GasPrice             uint64
+	GasPriceMultiplier   float64
+	MinGasPrice          uint64
+	MaxGasPrice          uint64
+	PendingTimeout       int
+	GasPriceBump         int
+	MaxReplacements      int
+	Confirmations        int
+	ConfirmTimeout       int
+	TxPollInterval       int
+	SenderStrategy       string
+	MinSenderBalance     uint64
+	MaxSendFailures      int
+	UnhealthyCooldown    int
+	BalanceCheckInterval int
+	LowBalanceThreshold  uint64
+	NonceGapTimeout      int
 }
 
 func (c *ServiceConfig) ImportPaletteAccount(chainId *big.Int) (
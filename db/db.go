@@ -17,12 +17,15 @@
 package db
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/boltdb/bolt"
 )
@@ -30,6 +33,13 @@ import (
 const (
 	maxNum   = 1000
 	capacity = 500000
+
+	// maxBlockHashNum is the number of latest palette block hash checkpoints kept in db,
+	// which is also the max reorg depth could be recovered by relayer.
+	maxBlockHashNum = 4096
+
+	// openTimeout is the max duration for waiting the file lock of db held by another process.
+	openTimeout = 3 * time.Second
 )
 
 var (
@@ -39,9 +49,12 @@ var (
 	bktPolyHeight    = []byte("PolyHeight")
 	bktPaletteHeight = []byte("PaletteHeight")
 	bktPaletteValSet = []byte("PaletteValSet")
-
-	// key for palette validators
-	validatorsKey = []byte("palette_validators")
+	bktPaletteHash   = []byte("PaletteBlockHash")
+	bktHeaderCommit  = []byte("PaletteHeaderCommit")
+	bktDeadLetter    = []byte("DeadLetter")
+	bktPaletteTx     = []byte("PaletteTx")
+	bktNonce         = []byte("Nonce")
+	bktPaletteDead   = []byte("PaletteDeadLetter")
 
 	// key for poly height
 	polyHeightKey    = []byte("poly_height")
@@ -53,12 +66,24 @@ var (
 
 var (
 	ErrOutOfNumber = errors.New("out of max number")
+	ErrNotFound    = errors.New("not found")
+	ErrInUse       = errors.New("database in use by another process")
 )
 
+// Entry is a key-value pair listed from bucket.
+type Entry struct {
+	Key   []byte
+	Value []byte
+}
+
 type BoltDB struct {
 	mtx      *sync.RWMutex
 	db       *bolt.DB
 	filePath string
+
+	// counts is the number of keys in `check`, `retry` and dead letter buckets, it's counted once
+	// opened and kept in memory, so that the backlog is reported without walking through buckets.
+	counts map[string]int
 }
 
 func NewBoltDB(filePath string) (*BoltDB, error) {
@@ -66,8 +91,11 @@ func NewBoltDB(filePath string) (*BoltDB, error) {
 		filePath = path.Join(filePath, "bolt.bin")
 	}
 
-	opt := &bolt.Options{InitialMmapSize: capacity}
+	opt := &bolt.Options{InitialMmapSize: capacity, Timeout: openTimeout}
 	db, err := bolt.Open(filePath, 0644, opt)
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("%w: %s is locked, stop the running relayer first", ErrInUse, filePath)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +104,7 @@ func NewBoltDB(filePath string) (*BoltDB, error) {
 		mtx:      new(sync.RWMutex),
 		db:       db,
 		filePath: filePath,
+		counts:   make(map[string]int),
 	}
 
 	list := [][]byte{
@@ -84,12 +113,25 @@ func NewBoltDB(filePath string) (*BoltDB, error) {
 		bktPolyHeight,
 		bktPaletteHeight,
 		bktPaletteValSet,
+		bktPaletteHash,
+		bktHeaderCommit,
+		bktDeadLetter,
+		bktPaletteTx,
+		bktNonce,
+		bktPaletteDead,
 	}
 	for _, name := range list {
 		if err := w.create(name); err != nil {
 			return nil, err
 		}
 	}
+	for _, name := range [][]byte{bktCheck, bktRetry, bktDeadLetter, bktPaletteDead} {
+		num, err := w.count(name)
+		if err != nil {
+			return nil, err
+		}
+		w.counts[string(name)] = num
+	}
 
 	return w, nil
 }
@@ -98,54 +140,119 @@ func (w *BoltDB) PutCheck(txHash string, v []byte) error {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
 
+	added := 0
 	handle := func(bkt *bolt.Bucket) error {
 		k, err := hex.DecodeString(txHash)
 		if err != nil {
 			return err
 		}
-		return bkt.Put(k, v)
+		added, err = putCounted(bkt, k, v)
+		return err
 	}
 
-	return w.update(bktCheck, handle)
+	if err := w.update(bktCheck, handle); err != nil {
+		return err
+	}
+	w.counts[string(bktCheck)] += added
+	return nil
 }
 
 func (w *BoltDB) DeleteCheck(txHash string) error {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
 
+	removed := 0
 	handle := func(bkt *bolt.Bucket) error {
 		k, err := hex.DecodeString(txHash)
 		if err != nil {
 			return err
 		}
-		return bkt.Delete(k)
+		removed, err = deleteCounted(bkt, k)
+		return err
 	}
 
-	return w.update(bktCheck, handle)
+	if err := w.update(bktCheck, handle); err != nil {
+		return err
+	}
+	w.counts[string(bktCheck)] -= removed
+	return nil
 }
 
+// PutRetry add cross transfer into `retry` bucket, the retry state of existing one is kept.
 func (w *BoltDB) PutRetry(k []byte) error {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
 
+	added := 0
 	handle := func(bkt *bolt.Bucket) error {
+		if hasKey(bkt, k) {
+			return nil
+		}
+		added = 1
 		return bkt.Put(k, emptyValue)
 	}
 
-	return w.update(bktRetry, handle)
+	if err := w.update(bktRetry, handle); err != nil {
+		return err
+	}
+	w.counts[string(bktRetry)] += added
+	return nil
+}
+
+// UpdateRetry record the retry state of cross transfer in `retry` bucket.
+func (w *BoltDB) UpdateRetry(k []byte, state []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	added := 0
+	handle := func(bkt *bolt.Bucket) (err error) {
+		added, err = putCounted(bkt, k, state)
+		return err
+	}
+
+	if err := w.update(bktRetry, handle); err != nil {
+		return err
+	}
+	w.counts[string(bktRetry)] += added
+	return nil
+}
+
+// GetRetryState return nil if cross transfer never failed or not exist.
+func (w *BoltDB) GetRetryState(k []byte) []byte {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	var state []byte
+	handle := func(raw []byte) error {
+		if len(raw) > 0 && !bytes.Equal(raw, emptyValue) {
+			state = copyBytes(raw)
+		}
+		return nil
+	}
+
+	_ = w.read(bktRetry, k, handle)
+	return state
 }
 
 func (w *BoltDB) DeleteRetry(k []byte) error {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
 
-	handle := func(bkt *bolt.Bucket) error {
-		return bkt.Delete(k)
+	removed := 0
+	handle := func(bkt *bolt.Bucket) (err error) {
+		removed, err = deleteCounted(bkt, k)
+		return err
 	}
 
-	return w.update(bktRetry, handle)
+	if err := w.update(bktRetry, handle); err != nil {
+		return err
+	}
+	w.counts[string(bktRetry)] -= removed
+	return nil
 }
 
+// GetAllCheck return at most `maxNum` poly transactions in `check` bucket, use `ListCheck` to
+// walk through the whole bucket.
 func (w *BoltDB) GetAllCheck() (map[string][]byte, error) {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
@@ -168,6 +275,8 @@ func (w *BoltDB) GetAllCheck() (map[string][]byte, error) {
 	return checkMap, nil
 }
 
+// GetAllRetry return at most `maxNum` cross transfers in `retry` bucket, use `ListRetry` to
+// walk through the whole bucket.
 func (w *BoltDB) GetAllRetry() ([][]byte, error) {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
@@ -190,6 +299,281 @@ func (w *BoltDB) GetAllRetry() ([][]byte, error) {
 	}
 }
 
+// ForEachRetry call `handler` with every cross transfer in `retry` bucket and its retry state, the state is
+// nil if the cross transfer never failed. unlike `GetAllRetry`, the number of cross transfers is not limited.
+func (w *BoltDB) ForEachRetry(handler func(k, state []byte)) error {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.db.View(func(btx *bolt.Tx) error {
+		return btx.Bucket(bktRetry).ForEach(func(k, v []byte) error {
+			var state []byte
+			if len(v) > 0 && !bytes.Equal(v, emptyValue) {
+				state = copyBytes(v)
+			}
+			handler(copyBytes(k), state)
+			return nil
+		})
+	})
+}
+
+// ListCheck return at most `limit` poly transactions in `check` bucket after `cursor`, and the cursor
+// to resume from. listing starts from the first key if cursor is nil, and the returned cursor is nil
+// once the end of bucket reached.
+func (w *BoltDB) ListCheck(cursor []byte, limit int) ([]*Entry, []byte, error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.list(bktCheck, cursor, limit)
+}
+
+// ListRetry return at most `limit` cross transfers in `retry` bucket after `cursor` in the same way as `ListCheck`.
+func (w *BoltDB) ListRetry(cursor []byte, limit int) ([]*Entry, []byte, error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.list(bktRetry, cursor, limit)
+}
+
+// CountCheck return the number of poly transactions in `check` bucket.
+func (w *BoltDB) CountCheck() (int, error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.counts[string(bktCheck)], nil
+}
+
+// CountRetry return the number of cross transfers in `retry` bucket.
+func (w *BoltDB) CountRetry() (int, error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.counts[string(bktRetry)], nil
+}
+
+// CountDeadLetter return the number of cross transfers in `dead letter` bucket.
+func (w *BoltDB) CountDeadLetter() (int, error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.counts[string(bktDeadLetter)], nil
+}
+
+// MoveRetryToDeadLetter remove cross transfer from `retry` bucket and record it in `dead letter` bucket
+// together with its last retry state.
+func (w *BoltDB) MoveRetryToDeadLetter(k []byte, state []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	var removed, added int
+	err := w.db.Update(func(tx *bolt.Tx) (err error) {
+		if removed, err = deleteCounted(tx.Bucket(bktRetry), k); err != nil {
+			return err
+		}
+		added, err = putCounted(tx.Bucket(bktDeadLetter), k, state)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.counts[string(bktRetry)] -= removed
+	w.counts[string(bktDeadLetter)] += added
+	return nil
+}
+
+// RequeueDeadLetter move cross transfer from `dead letter` bucket back to `retry` bucket with a fresh retry state.
+func (w *BoltDB) RequeueDeadLetter(k []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	added := 0
+	err := w.db.Update(func(tx *bolt.Tx) (err error) {
+		dead := tx.Bucket(bktDeadLetter)
+		if !hasKey(dead, k) {
+			return ErrNotFound
+		}
+		if err := dead.Delete(k); err != nil {
+			return err
+		}
+		added, err = putCounted(tx.Bucket(bktRetry), k, emptyValue)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.counts[string(bktDeadLetter)]--
+	w.counts[string(bktRetry)] += added
+	return nil
+}
+
+// ForEachDeadLetter call `handler` with every cross transfer in `dead letter` bucket and its retry state,
+// and the number of dead letters is not limited.
+func (w *BoltDB) ForEachDeadLetter(handler func(k, state []byte)) error {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.db.View(func(btx *bolt.Tx) error {
+		return btx.Bucket(bktDeadLetter).ForEach(func(k, v []byte) error {
+			handler(copyBytes(k), copyBytes(v))
+			return nil
+		})
+	})
+}
+
+// PutPaletteTx record the palette transaction which is queued or in-flight, so that it can be replayed
+// after relayer restarted.
+func (w *BoltDB) PutPaletteTx(k []byte, v []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	handle := func(bkt *bolt.Bucket) error {
+		return bkt.Put(k, v)
+	}
+
+	return w.update(bktPaletteTx, handle)
+}
+
+func (w *BoltDB) DeletePaletteTx(k []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	handle := func(bkt *bolt.Bucket) error {
+		return bkt.Delete(k)
+	}
+
+	return w.update(bktPaletteTx, handle)
+}
+
+// ForEachPaletteTx call `handler` with every palette transaction in `palette tx` bucket in order of keys.
+func (w *BoltDB) ForEachPaletteTx(handler func(k, v []byte)) error {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.db.View(func(btx *bolt.Tx) error {
+		return btx.Bucket(bktPaletteTx).ForEach(func(k, v []byte) error {
+			handler(copyBytes(k), copyBytes(v))
+			return nil
+		})
+	})
+}
+
+// CountPaletteDeadLetter return the number of palette transactions in `palette dead letter` bucket.
+func (w *BoltDB) CountPaletteDeadLetter() (int, error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.counts[string(bktPaletteDead)], nil
+}
+
+// MovePaletteTxToDeadLetter remove palette transaction which can never succeed from `palette tx` bucket,
+// and keep it in `palette dead letter` bucket for operators to inspect.
+func (w *BoltDB) MovePaletteTxToDeadLetter(k []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	added := 0
+	err := w.db.Update(func(tx *bolt.Tx) (err error) {
+		raw := tx.Bucket(bktPaletteTx).Get(k)
+		if raw == nil {
+			return ErrNotFound
+		}
+		v := copyBytes(raw)
+		if err := tx.Bucket(bktPaletteTx).Delete(k); err != nil {
+			return err
+		}
+		added, err = putCounted(tx.Bucket(bktPaletteDead), k, v)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.counts[string(bktPaletteDead)] += added
+	return nil
+}
+
+// PutNonce record the nonce state of palette sender `account`.
+func (w *BoltDB) PutNonce(account []byte, v []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	handle := func(bkt *bolt.Bucket) error {
+		return bkt.Put(account, v)
+	}
+
+	return w.update(bktNonce, handle)
+}
+
+// GetNonce return the nonce state of palette sender `account`, and nil if it's not recorded.
+func (w *BoltDB) GetNonce(account []byte) []byte {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	var v []byte
+	handle := func(raw []byte) error {
+		if raw != nil {
+			v = copyBytes(raw)
+		}
+		return nil
+	}
+
+	_ = w.read(bktNonce, account, handle)
+
+	return v
+}
+
+// PutHeaderCommit record the palette headers which are submitted to poly chain in transaction `txHash`
+// but not confirmed yet.
+func (w *BoltDB) PutHeaderCommit(txHash string, v []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	handle := func(bkt *bolt.Bucket) error {
+		k, err := hex.DecodeString(txHash)
+		if err != nil {
+			return err
+		}
+		return bkt.Put(k, v)
+	}
+
+	return w.update(bktHeaderCommit, handle)
+}
+
+func (w *BoltDB) DeleteHeaderCommit(txHash string) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	handle := func(bkt *bolt.Bucket) error {
+		k, err := hex.DecodeString(txHash)
+		if err != nil {
+			return err
+		}
+		return bkt.Delete(k)
+	}
+
+	return w.update(bktHeaderCommit, handle)
+}
+
+func (w *BoltDB) GetAllHeaderCommit() (map[string][]byte, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	commitMap := make(map[string][]byte)
+
+	handle := func(k, v []byte) error {
+		commitMap[hex.EncodeToString(k)] = copyBytes(v)
+		if len(commitMap) >= maxNum {
+			return ErrOutOfNumber
+		}
+		return nil
+	}
+
+	if err := w.foreach(bktHeaderCommit, handle); err != nil {
+		return nil, err
+	}
+
+	return commitMap, nil
+}
+
 func (w *BoltDB) UpdatePolyHeight(h uint32) error {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
@@ -249,31 +633,139 @@ func (w *BoltDB) GetPaletteHeight() uint64 {
 	return h
 }
 
-func (w *BoltDB) PutValSet(valset []byte) error {
+// PutPaletteBlockHash record the hash of processed palette block as checkpoint, and remove
+// checkpoints which are older than `maxBlockHashNum` blocks.
+func (w *BoltDB) PutPaletteBlockHash(height uint64, hash []byte) error {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
 
 	handle := func(bkt *bolt.Bucket) error {
-		return bkt.Put(validatorsKey, valset)
+		if err := bkt.Put(heightKey(height), hash); err != nil {
+			return err
+		}
+		if height < maxBlockHashNum {
+			return nil
+		}
+
+		expired := heightKey(height - maxBlockHashNum)
+		keys := make([][]byte, 0)
+		c := bkt.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, expired) <= 0; k, _ = c.Next() {
+			keys = append(keys, copyBytes(k))
+		}
+		return deleteKeys(bkt, keys)
+	}
+
+	return w.update(bktPaletteHash, handle)
+}
+
+// GetPaletteBlockHash return nil if there is no checkpoint at `height`.
+func (w *BoltDB) GetPaletteBlockHash(height uint64) []byte {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	var hash []byte
+	handle := func(raw []byte) error {
+		if len(raw) > 0 {
+			hash = copyBytes(raw)
+		}
+		return nil
+	}
+
+	_ = w.read(bktPaletteHash, heightKey(height), handle)
+	return hash
+}
+
+// DeletePaletteBlockHashFrom remove checkpoints whose height is not lower than `height`.
+func (w *BoltDB) DeletePaletteBlockHashFrom(height uint64) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	handle := func(bkt *bolt.Bucket) error {
+		keys := make([][]byte, 0)
+		c := bkt.Cursor()
+		for k, _ := c.Seek(heightKey(height)); k != nil; k, _ = c.Next() {
+			keys = append(keys, copyBytes(k))
+		}
+		return deleteKeys(bkt, keys)
+	}
+
+	return w.update(bktPaletteHash, handle)
+}
+
+// PutValSet record the palette validators which changed in epoch block at `height`.
+func (w *BoltDB) PutValSet(height uint64, valset []byte) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	handle := func(bkt *bolt.Bucket) error {
+		return bkt.Put(heightKey(height), valset)
 	}
 
 	return w.update(bktPaletteValSet, handle)
 }
 
-func (w *BoltDB) GetValSet() ([]byte, error) {
+// GetValSet return nil if there is no validators recorded at `height`.
+func (w *BoltDB) GetValSet(height uint64) ([]byte, error) {
 	w.mtx.RLock()
 	defer w.mtx.RUnlock()
 
 	var enc []byte
 	handle := func(raw []byte) error {
-		enc = copyBytes(raw)
+		if len(raw) > 0 {
+			enc = copyBytes(raw)
+		}
 		return nil
 	}
-	_ = w.read(bktPaletteValSet, validatorsKey, handle)
+	_ = w.read(bktPaletteValSet, heightKey(height), handle)
 
 	return enc, nil
 }
 
+// GetValSetFrom return the validators recorded at heights not lower than `height`, and they are
+// sorted in ascending order of height.
+func (w *BoltDB) GetValSetFrom(height uint64) ([]uint64, [][]byte, error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	heights := make([]uint64, 0)
+	list := make([][]byte, 0)
+	err := w.db.View(func(btx *bolt.Tx) error {
+		c := btx.Bucket(bktPaletteValSet).Cursor()
+		for k, v := c.Seek(heightKey(height)); k != nil; k, v = c.Next() {
+			if len(k) != 8 {
+				continue
+			}
+			heights = append(heights, binary.BigEndian.Uint64(k))
+			list = append(list, copyBytes(v))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return heights, list, nil
+}
+
+// DeleteValSetFrom remove validators recorded at heights not lower than `height`.
+func (w *BoltDB) DeleteValSetFrom(height uint64) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	handle := func(bkt *bolt.Bucket) error {
+		keys := make([][]byte, 0)
+		c := bkt.Cursor()
+		for k, _ := c.Seek(heightKey(height)); k != nil; k, _ = c.Next() {
+			if len(k) == 8 {
+				keys = append(keys, copyBytes(k))
+			}
+		}
+		return deleteKeys(bkt, keys)
+	}
+
+	return w.update(bktPaletteValSet, handle)
+}
+
 func (w *BoltDB) Close() {
 	w.mtx.Lock()
 	_ = w.db.Close()
@@ -327,6 +819,80 @@ func (w *BoltDB) foreach(bktName []byte, handler foreachHandler) error {
 	})
 }
 
+func (w *BoltDB) list(bktName []byte, cursor []byte, limit int) ([]*Entry, []byte, error) {
+	list := make([]*Entry, 0)
+	var next []byte
+
+	err := w.db.View(func(btx *bolt.Tx) error {
+		c := btx.Bucket(bktName).Cursor()
+		k, v := c.First()
+		if cursor != nil {
+			if k, v = c.Seek(cursor); k != nil && bytes.Equal(k, cursor) {
+				k, v = c.Next()
+			}
+		}
+		for ; k != nil && len(list) < limit; k, v = c.Next() {
+			list = append(list, &Entry{Key: copyBytes(k), Value: copyBytes(v)})
+		}
+		if k != nil && len(list) > 0 {
+			next = list[len(list)-1].Key
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return list, next, nil
+}
+
+// hasKey return true if `k` exists in bucket.
+func hasKey(bkt *bolt.Bucket, k []byte) bool {
+	key, _ := bkt.Cursor().Seek(k)
+	return key != nil && bytes.Equal(key, k)
+}
+
+// putCounted put `k` into bucket, and return 1 if it's a new key.
+func putCounted(bkt *bolt.Bucket, k, v []byte) (int, error) {
+	added := 0
+	if !hasKey(bkt, k) {
+		added = 1
+	}
+	return added, bkt.Put(k, v)
+}
+
+// deleteCounted delete `k` from bucket, and return 1 if it existed.
+func deleteCounted(bkt *bolt.Bucket, k []byte) (int, error) {
+	if !hasKey(bkt, k) {
+		return 0, nil
+	}
+	return 1, bkt.Delete(k)
+}
+
+func (w *BoltDB) count(bktName []byte) (int, error) {
+	var num int
+	err := w.db.View(func(btx *bolt.Tx) error {
+		num = btx.Bucket(bktName).Stats().KeyN
+		return nil
+	})
+	return num, err
+}
+
+func deleteKeys(bkt *bolt.Bucket, keys [][]byte) error {
+	for _, k := range keys {
+		if err := bkt.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// heightKey encode height in big endian, so that keys are sorted by height in bucket.
+func heightKey(height uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, height)
+	return key
+}
+
 func copyBytes(src []byte) []byte {
 	dst := make([]byte, len(src))
 	copy(dst, src)
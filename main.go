@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -57,7 +58,35 @@ func setupApp() *cli.App {
 		cmd.DebugFlag,
 		cmd.LogDir,
 	}
-	app.Commands = []cli.Command{}
+	app.Commands = []cli.Command{
+		{
+			Name:  "deadletter",
+			Usage: "Inspect or requeue cross chain transfers which exceed max retry attempts, relayer should be stopped",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "List all of dead letters",
+					Action: listDeadLetters,
+				},
+				{
+					Name:      "requeue",
+					Usage:     "Move dead letters back to retry queue, they are retried once relayer started again",
+					ArgsUsage: "<key>... | all",
+					Action:    requeueDeadLetters,
+				},
+			},
+		},
+		{
+			Name:   "valset",
+			Usage:  "List the history of palette validators detected by relayer, relayer should be stopped",
+			Action: listValSetHistory,
+		},
+		{
+			Name:   "backlog",
+			Usage:  "Show the number of cross chain transfers waiting in db, relayer should be stopped",
+			Action: showBacklog,
+		},
+	}
 	app.Before = func(context *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
 		return nil
@@ -109,20 +138,126 @@ func startServer(ctx *cli.Context) {
 		return
 	}
 
-	var boltDB *db.BoltDB
-	if srvConfig.BoltDbPath == "" {
-		boltDB, err = db.NewBoltDB("boltdb")
-	} else {
-		boltDB, err = db.NewBoltDB(srvConfig.BoltDBPath())
-	}
+	boltDB, err := openBoltDB(srvConfig)
 	if err != nil {
 		log.Fatalf("db.NewWaitingDB error:%s", err)
 		return
 	}
 
-	initPolyServer(srvConfig, polySdk, paletteSDK, boltDB)
-	initPLTServer(srvConfig, polySdk, paletteSDK, boltDB)
+	runCtx, cancel := context.WithCancel(context.Background())
+	polyMgr := initPolyServer(runCtx, srvConfig, polySdk, paletteSDK, boltDB)
+	pltMgr := initPLTServer(runCtx, srvConfig, polySdk, paletteSDK, boltDB)
 	waitToExit()
+
+	// stop managers before closing db, poly manager waits for palette transactions in flight.
+	cancel()
+	polyMgr.Stop()
+	pltMgr.Stop()
+	boltDB.Close()
+	log.Infof("startServer - relayer exit")
+}
+
+func openBoltDB(srvConfig *config.ServiceConfig) (*db.BoltDB, error) {
+	if srvConfig.BoltDbPath == "" {
+		return db.NewBoltDB("boltdb")
+	}
+	return db.NewBoltDB(srvConfig.BoltDBPath())
+}
+
+// openCommandDB open bolt db configured in config file for offline commands.
+func openCommandDB(ctx *cli.Context) (*db.BoltDB, error) {
+	srvConfig := config.NewServiceConfig(ctx.GlobalString(cmd.GetFlagName(cmd.ConfigPathFlag)))
+	if srvConfig == nil {
+		return nil, fmt.Errorf("create config failed")
+	}
+	return openBoltDB(srvConfig)
+}
+
+func listDeadLetters(ctx *cli.Context) error {
+	boltDB, err := openCommandDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer boltDB.Close()
+
+	list, err := manager.ListDeadLetters(boltDB)
+	if err != nil {
+		return err
+	}
+	for _, v := range list {
+		fmt.Printf("key: %s\ntx: %s, height: %d, attempts: %d\nlast error: %s\n\n",
+			v.Key, v.TxId, v.Height, v.Attempts, v.LastErr)
+	}
+	fmt.Printf("%d dead letters\n", len(list))
+	return nil
+}
+
+func requeueDeadLetters(ctx *cli.Context) error {
+	keys := ctx.Args()
+	if len(keys) == 0 {
+		return fmt.Errorf("dead letter keys or `all` required")
+	}
+
+	boltDB, err := openCommandDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer boltDB.Close()
+
+	if len(keys) == 1 && keys[0] == "all" {
+		list, err := manager.ListDeadLetters(boltDB)
+		if err != nil {
+			return err
+		}
+		keys = make([]string, 0, len(list))
+		for _, v := range list {
+			keys = append(keys, v.Key)
+		}
+	}
+	for _, key := range keys {
+		if err := manager.RequeueDeadLetter(boltDB, key); err != nil {
+			return fmt.Errorf("requeue dead letter %s err: %s", key, err)
+		}
+		fmt.Printf("dead letter %s requeued\n", key)
+	}
+	return nil
+}
+
+func listValSetHistory(ctx *cli.Context) error {
+	boltDB, err := openCommandDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer boltDB.Close()
+
+	list, err := manager.ListValSetHistory(boltDB)
+	if err != nil {
+		return err
+	}
+	for _, v := range list {
+		fmt.Printf("height: %d, hash: %s, validators: %d\n", v.Height, v.Hash.Hex(), len(v.Validators))
+		for _, addr := range v.Validators {
+			fmt.Printf("  %s\n", addr.Hex())
+		}
+	}
+	fmt.Printf("%d epochs\n", len(list))
+	return nil
+}
+
+func showBacklog(ctx *cli.Context) error {
+	boltDB, err := openCommandDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer boltDB.Close()
+
+	backlog, err := manager.GetBacklog(boltDB)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("check: %d\nretry: %d\ndead letter: %d\npalette dead letter: %d\n", backlog.Check, backlog.Retry,
+		backlog.DeadLetter, backlog.PaletteDeadLetter)
+	return nil
 }
 
 func setUpPoly(poly *sdk.PolySdk, RpcAddr string) error {
@@ -150,11 +285,12 @@ func waitToExit() {
 }
 
 func initPLTServer(
+	ctx context.Context,
 	srvConfig *config.ServiceConfig,
 	polySDK *sdk.PolySdk,
 	paletteSDK *pltcli.Client,
 	boltDB *db.BoltDB,
-) {
+) *manager.PaletteManager {
 
 	mgr, err := manager.NewPaletteManager(
 		srvConfig,
@@ -168,17 +304,17 @@ func initPLTServer(
 		panic(fmt.Sprintf("initPLTServer - eth service start err: %s", err.Error()))
 	}
 
-	go mgr.MonitorChain()
-	go mgr.MonitorDeposit()
-	go mgr.CheckDeposit()
+	mgr.Start(ctx)
+	return mgr
 }
 
 func initPolyServer(
+	ctx context.Context,
 	srvConfig *config.ServiceConfig,
 	polySDK *sdk.PolySdk,
 	paletteSDK *pltcli.Client,
 	boltDB *db.BoltDB,
-) {
+) *manager.PolyManager {
 
 	mgr, err := manager.NewPolyManager(
 		srvConfig,
@@ -191,7 +327,8 @@ func initPolyServer(
 		panic(fmt.Sprintf("initPolyServer - PolyServer service start failed: %v", err))
 	}
 
-	go mgr.MonitorChain()
+	mgr.Start(ctx)
+	return mgr
 }
 
 func main() {
@@ -0,0 +1,165 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/palettechain/palette-relayer/log"
+)
+
+const (
+	// defaultBalanceCheckInterval is used if `PaletteConfig.BalanceCheckInterval` is not configured.
+	defaultBalanceCheckInterval = time.Minute
+)
+
+// balance level of sender account.
+const (
+	balanceUnknown = iota
+	balanceOK
+	balanceLow
+	balanceDepleted
+)
+
+// SenderBalance is the latest balance of palette sender account polled by balance monitor.
+type SenderBalance struct {
+	Address    string
+	Balance    *big.Int
+	UpdateTime time.Time
+	Low        bool
+	Depleted   bool
+}
+
+// monitorBalances poll balances of senders and report their load every `PaletteConfig.BalanceCheckInterval`
+// until `ctx` is done.
+func (m *PolyManager) monitorBalances(ctx context.Context) {
+	m.checkBalances()
+	m.logSenderStats()
+
+	ticker := time.NewTicker(m.balanceCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.checkBalances()
+			m.logSenderStats()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkBalances refresh balances of senders and alert if any of them is lower than `PaletteConfig.LowBalanceThreshold`.
+// senders whose balance is lower than `PaletteConfig.MinSenderBalance` are depleted, and no more palette
+// transactions are dispatched to them until they are refilled.
+func (m *PolyManager) checkBalances() {
+	low := new(big.Int).SetUint64(m.config.PaletteConfig.LowBalanceThreshold)
+	floor := new(big.Int).SetUint64(m.config.PaletteConfig.MinSenderBalance)
+
+	for _, s := range m.senders {
+		addr := s.acc.Address.Hex()
+		balance, err := m.paletteCli.BalanceAt(context.Background(), s.acc.Address, nil)
+		if err != nil {
+			log.Errorf("PolyManager checkBalances - get balance of sender %s error: %s", addr, err)
+			continue
+		}
+
+		level := balanceOK
+		switch {
+		case balance.Cmp(floor) < 0:
+			level = balanceDepleted
+			log.Errorf("PolyManager checkBalances - sender %s is depleted, balance %s is lower than %s, "+
+				"dispatch paused", addr, balance, floor)
+		case balance.Cmp(low) < 0:
+			level = balanceLow
+			log.Warnf("PolyManager checkBalances - balance of sender %s is low, %s is lower than %s",
+				addr, balance, low)
+		default:
+			debug("PolyManager checkBalances - balance of sender %s is %s", addr, balance)
+		}
+
+		if prev := s.setBalance(balance, level); prev > balanceOK && level == balanceOK {
+			log.Infof("PolyManager checkBalances - sender %s is refilled, balance %s", addr, balance)
+		}
+	}
+
+	list := m.SenderBalances()
+	summary := make([]string, 0, len(list))
+	for _, v := range list {
+		balance := "unknown"
+		if v.Balance != nil {
+			balance = v.Balance.String()
+		}
+		summary = append(summary, fmt.Sprintf("%s: %s", v.Address, balance))
+	}
+	log.Infof("PolyManager checkBalances - balances of %d senders: %s", len(list), strings.Join(summary, ", "))
+}
+
+// SenderBalances return the balances of senders polled by balance monitor, the balance is nil if
+// it's never polled successfully.
+func (m *PolyManager) SenderBalances() []*SenderBalance {
+	list := make([]*SenderBalance, 0, len(m.senders))
+	for _, s := range m.senders {
+		s.balanceMtx.RLock()
+		v := &SenderBalance{
+			Address:    s.acc.Address.Hex(),
+			UpdateTime: s.balanceTime,
+			Low:        s.balanceLevel >= balanceLow,
+			Depleted:   s.balanceLevel == balanceDepleted,
+		}
+		if s.balance != nil {
+			v.Balance = new(big.Int).Set(s.balance)
+		}
+		s.balanceMtx.RUnlock()
+		list = append(list, v)
+	}
+	return list
+}
+
+// setBalance record the polled balance of sender, and return the previous balance level.
+func (s *PaletteSender) setBalance(balance *big.Int, level int) int {
+	s.balanceMtx.Lock()
+	defer s.balanceMtx.Unlock()
+
+	prev := s.balanceLevel
+	s.balance, s.balanceTime, s.balanceLevel = balance, time.Now(), level
+	return prev
+}
+
+// polledBalance return the balance polled by balance monitor, and nil if it's never polled or
+// the last polling is older than `maxAge`, so that callers should query palette node instead.
+func (s *PaletteSender) polledBalance(maxAge time.Duration) *big.Int {
+	s.balanceMtx.RLock()
+	defer s.balanceMtx.RUnlock()
+
+	if s.balance == nil || time.Since(s.balanceTime) > maxAge {
+		return nil
+	}
+	return s.balance
+}
+
+func (m *PolyManager) balanceCheckInterval() time.Duration {
+	if m.config.PaletteConfig.BalanceCheckInterval <= 0 {
+		return defaultBalanceCheckInterval
+	}
+	return time.Duration(m.config.PaletteConfig.BalanceCheckInterval) * time.Second
+}
@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakePolyManagerBalanceMonitor(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.SenderStrategy = SenderRoundRobin
+	env.cfg.PaletteConfig.MinSenderBalance = 10
+	env.cfg.PaletteConfig.LowBalanceThreshold = 100
+	signer := fake.NewSigner(fakeSideChainID, fake.Key(1), fake.Key(2), fake.Key(3))
+	mgr, err := newPolyManager(env.cfg, 0, env.poly, env.palette, env.palette, signer, signer.Accounts(), env.db)
+	require.NoError(t, err)
+
+	for _, v := range mgr.SenderBalances() {
+		assert.Nil(t, v.Balance)
+	}
+
+	for i, balance := range []int64{5, 50, 500} {
+		env.palette.SetBalance(mgr.senders[i].acc.Address, big.NewInt(balance))
+	}
+	mgr.checkBalances()
+
+	balances := mgr.SenderBalances()
+	require.Len(t, balances, 3)
+	for i, expect := range []struct {
+		balance       int64
+		low, depleted bool
+	}{{5, true, true}, {50, true, false}, {500, false, false}} {
+		assert.Equal(t, mgr.senders[i].acc.Address.Hex(), balances[i].Address)
+		assert.Equal(t, big.NewInt(expect.balance), balances[i].Balance)
+		assert.Equal(t, expect.low, balances[i].Low)
+		assert.Equal(t, expect.depleted, balances[i].Depleted)
+	}
+
+	// dispatch to depleted sender is paused until balance monitor finds it refilled.
+	env.palette.SetBalance(mgr.senders[0].acc.Address, big.NewInt(1000))
+	for i := 0; i < 4; i++ {
+		assert.NotEqual(t, mgr.senders[0], mgr.selectSender())
+	}
+	mgr.checkBalances()
+	assert.False(t, mgr.SenderBalances()[0].Depleted)
+	picked := make(map[*PaletteSender]bool)
+	for i := 0; i < 3; i++ {
+		picked[mgr.selectSender()] = true
+	}
+	assert.True(t, picked[mgr.senders[0]])
+
+	// balances are polled in background once manager started.
+	env.palette.SetBalance(mgr.senders[2].acc.Address, big.NewInt(0))
+	env.cfg.PaletteConfig.BalanceCheckInterval = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr.Start(ctx)
+	defer func() {
+		cancel()
+		mgr.Stop()
+	}()
+	assert.Eventually(t, func() bool {
+		return mgr.SenderBalances()[2].Depleted
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,101 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/palettechain/palette-relayer/log"
+	polycm "github.com/polynetwork/poly/common"
+)
+
+// defaultCheckTimeout is used if `PaletteConfig.CheckTimeout` is not configured.
+const defaultCheckTimeout = 10 * time.Minute
+
+// putCheck record the cross transfer whose proof is submitted to poly chain in transaction `txHash`.
+func (m *PaletteManager) putCheck(txHash string, v []byte) error {
+	record := &CheckRecord{submitTime: time.Now().Unix(), crossTx: v}
+	sink := polycm.NewZeroCopySink(nil)
+	record.Serialization(sink)
+	return m.db.PutCheck(txHash, sink.Bytes())
+}
+
+// decodeCheckRecord deserialize the value of `check` bucket. entries written before submit time recorded
+// only contain the cross transfer, and their submit time is 0.
+func decodeCheckRecord(raw []byte) (*CheckRecord, error) {
+	record := new(CheckRecord)
+	source := polycm.NewZeroCopySource(raw)
+	if err := record.Deserialization(source); err == nil && source.Len() == 0 {
+		if _, err := deserializeCrossTransfer(record.crossTx); err == nil {
+			return record, nil
+		}
+	}
+
+	if _, err := deserializeCrossTransfer(raw); err != nil {
+		return nil, err
+	}
+	return &CheckRecord{crossTx: raw}, nil
+}
+
+// checkExpired return true if the poly transaction is not confirmed within `CheckTimeout`. the timeout of
+// entries without submit time starts from now.
+func (m *PaletteManager) checkExpired(txHash string, record *CheckRecord) bool {
+	if record.submitTime == 0 {
+		if err := m.putCheck(txHash, record.crossTx); err != nil {
+			log.Errorf("PaletteManager checkExpired - m.putCheck error: %s", err)
+		}
+		return false
+	}
+	return time.Since(time.Unix(record.submitTime, 0)) > m.checkTimeout()
+}
+
+// checkTimedOut move the cross transfer whose poly transaction is never confirmed back to `retry` bucket,
+// unless it is already done on poly chain by another transaction.
+func (m *PaletteManager) checkTimedOut(txHash string, v []byte) {
+	crossTx, err := deserializeCrossTransfer(v)
+	if err != nil {
+		log.Errorf("PaletteManager checkTimedOut - deserialize cross transfer err: %s", err)
+		return
+	}
+
+	timeouts := atomic.AddUint64(&m.checkTimeouts, 1)
+	if !m.checkCrossChainEvent(recoverMakeTxParams(crossTx.value)) {
+		log.Infof("PaletteManager checkTimedOut - poly tx %s timed out, but plt_tx %s already on poly",
+			txHash, txIdHex(crossTx.txId))
+	} else {
+		if err := m.db.PutRetry(v); err != nil {
+			log.Errorf("PaletteManager checkTimedOut - m.db.PutRetry error: %s", err)
+			return
+		}
+		m.deposits.delay(v, crossTx.height, time.Now().Unix())
+		log.Warnf("PaletteManager checkTimedOut - poly tx %s of plt_tx %s not confirmed in %s, resubmit it later, "+
+			"%d poly txs timed out", txHash, txIdHex(crossTx.txId), m.checkTimeout(), timeouts)
+	}
+
+	if err := m.db.DeleteCheck(txHash); err != nil {
+		log.Errorf("PaletteManager checkTimedOut - m.db.DeleteCheck error: %s", err)
+	}
+}
+
+// checkTimeout return the max duration for waiting poly transaction of cross transfer confirmed.
+func (m *PaletteManager) checkTimeout() time.Duration {
+	if m.config.PaletteConfig.CheckTimeout <= 0 {
+		return defaultCheckTimeout
+	}
+	return time.Duration(m.config.PaletteConfig.CheckTimeout) * time.Second
+}
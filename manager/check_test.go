@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/polynetwork/eth-contracts/go_abi/eccm_abi"
+	polycm "github.com/polynetwork/poly/common"
+	ccm "github.com/polynetwork/poly/native/service/cross_chain_manager/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expireCheck make the poly tx in `check` bucket submitted long ago.
+func expireCheck(t *testing.T, mgr *PaletteManager, txHash string, v []byte) {
+	record := &CheckRecord{submitTime: time.Now().Add(-2 * mgr.checkTimeout()).Unix(), crossTx: v}
+	sink := polycm.NewZeroCopySink(nil)
+	record.Serialization(sink)
+	require.NoError(t, mgr.db.PutCheck(txHash, sink.Bytes()))
+}
+
+func TestFakePaletteCheckTimeout(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	calls := 0
+	env.poly.ImportOuterTransferHook = func(_ *fake.ImportedTransfer) error {
+		calls++
+		return nil
+	}
+
+	_, evt := env.emitLockEvent(1)
+	env.palette.Mine(int(mgr.safeBlockDistance()) + 1)
+	refHeight := syncPalette(t, mgr)
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(retryList))
+	v := retryList[0]
+
+	// poly tx is never included.
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	txHash := env.poly.ImportedTransfers()[0].TxHash
+	env.poly.DropTx(txHash)
+
+	// poly tx is waited until timeout.
+	require.NoError(t, mgr.checkLockEvents())
+	checkMap, err := env.db.GetAllCheck()
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(checkMap))
+
+	// cross transfer of timed out poly tx is resubmitted.
+	expireCheck(t, mgr, txHash, v)
+	require.NoError(t, mgr.checkLockEvents())
+	checkMap, err = env.db.GetAllCheck()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(checkMap))
+	retryList, err = env.db.GetAllRetry()
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(retryList))
+	assert.Equal(t, uint64(1), mgr.checkTimeouts)
+
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	assert.Equal(t, 2, calls)
+	checkMap, err = env.db.GetAllCheck()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(checkMap))
+
+	// timed out poly tx is dropped if cross transfer is already done by another tx.
+	txHash = env.poly.ImportedTransfers()[1].TxHash
+	env.poly.DropTx(txHash)
+	expireCheck(t, mgr, txHash, v)
+	key := mgr.formatStorageKey(ccm.DONE_TX, recoverMakeTxParams(evt.Rawdata).CrossChainID)
+	env.poly.SetStorage(polyCrossChainMgrContract.ToHexString(), key, []byte{1})
+	require.NoError(t, mgr.checkLockEvents())
+	checkMap, err = env.db.GetAllCheck()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(checkMap))
+	retryList, err = env.db.GetAllRetry()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(retryList))
+	assert.Equal(t, 0, mgr.deposits.len())
+}
+
+func TestDecodeCheckRecord(t *testing.T) {
+	_, sink := serializeCrossTransfer(&eccm_abi.EthCrossChainManagerCrossChainEvent{TxId: []byte{1}}, 10)
+	v := sink.Bytes()
+
+	// entries written before submit time recorded.
+	record, err := decodeCheckRecord(v)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), record.submitTime)
+	assert.Equal(t, v, record.crossTx)
+
+	expect := &CheckRecord{submitTime: 100, crossTx: v}
+	sink = polycm.NewZeroCopySink(nil)
+	expect.Serialization(sink)
+	record, err = decodeCheckRecord(sink.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, expect, record)
+
+	_, err = decodeCheckRecord([]byte{1})
+	assert.Error(t, err)
+}
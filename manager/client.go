@@ -0,0 +1,153 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	pltcm "github.com/ethereum/go-ethereum/common"
+	plttyp "github.com/ethereum/go-ethereum/core/types"
+	pltcli "github.com/ethereum/go-ethereum/ethclient"
+	"github.com/palettechain/palette-relayer/utils/palette"
+	"github.com/polynetwork/eth-contracts/go_abi/eccm_abi"
+	polysdk "github.com/polynetwork/poly-go-sdk"
+	polysdkcm "github.com/polynetwork/poly-go-sdk/common"
+	polycm "github.com/polynetwork/poly/common"
+	polytypes "github.com/polynetwork/poly/core/types"
+)
+
+// PaletteSource is the palette chain view used by `PaletteManager`: block headers, cross chain
+// events emitted by the ECCM contract and storage proofs of the ECCD contract.
+type PaletteSource interface {
+	GetNodeHeight() (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*plttyp.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*plttyp.Block, error)
+	FilterCrossChainEvent(start, end uint64) ([]*eccm_abi.EthCrossChainManagerCrossChainEvent, error)
+	GetProof(contractAddress string, key string, blockHeight string) ([]byte, error)
+}
+
+// PaletteTxClient is the subset of palette client used by `PaletteSender` to send and track
+// transactions, it is satisfied by `*ethclient.Client`.
+type PaletteTxClient interface {
+	PendingNonceAt(ctx context.Context, account pltcm.Address) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*plttyp.Header, error)
+	BalanceAt(ctx context.Context, account pltcm.Address, blockNumber *big.Int) (*big.Int, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	SendTransaction(ctx context.Context, tx *plttyp.Transaction, args bind.PrivateTxArgs) error
+	TransactionByHash(ctx context.Context, hash pltcm.Hash) (*plttyp.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, txHash pltcm.Hash) (*plttyp.Receipt, error)
+}
+
+// CrossChainData is the subset of palette ECCD contract calls used by `PolyManager`,
+// it is satisfied by `*eccd_abi.EthCrossChainData`.
+type CrossChainData interface {
+	GetCurEpochStartHeight(opts *bind.CallOpts) (uint32, error)
+	GetCurEpochConPubKeyBytes(opts *bind.CallOpts) ([]byte, error)
+	CheckIfFromChainTxExist(opts *bind.CallOpts, fromChainId uint64, fromChainTx [32]byte) (bool, error)
+}
+
+// TxSigner signs palette transactions for relayer accounts, it is satisfied by `*keystore.PaletteKeyStore`.
+type TxSigner interface {
+	SignTransaction(tx *plttyp.Transaction, acc accounts.Account) (*plttyp.Transaction, error)
+	GetChainId() uint64
+}
+
+// PolyClient is the subset of poly sdk used by both managers.
+type PolyClient interface {
+	GetStorage(contractAddress string, key []byte) ([]byte, error)
+	GetCurrentBlockHeight() (uint32, error)
+	GetHeaderByHeight(height uint32) (*polytypes.Header, error)
+	GetBlockHeightByTxHash(txHash string) (uint32, error)
+	GetMerkleProof(blockHeight, rootHeight uint32) (*polysdkcm.MerkleProof, error)
+	GetCrossStatesProof(height uint32, key string) (*polysdkcm.MerkleProof, error)
+	GetSmartContractEvent(txHash string) (*polysdkcm.SmartContactEvent, error)
+	GetSmartContractEventByBlock(height uint32) ([]*polysdkcm.SmartContactEvent, error)
+	SyncBlockHeader(chainId uint64, address polycm.Address, headers [][]byte, signer *polysdk.Account) (polycm.Uint256, error)
+	ImportOuterTransfer(sourceChainId uint64, txData []byte, height uint32, proof []byte,
+		relayerAddress []byte, HeaderOrCrossChainMsg []byte, signer *polysdk.Account) (polycm.Uint256, error)
+}
+
+// paletteSource implements `PaletteSource` with palette client, ECCM contract binding and
+// the json rpc helpers in `utils/palette`.
+type paletteSource struct {
+	*pltcli.Client
+	eccm *eccm_abi.EthCrossChainManager
+}
+
+func (s *paletteSource) GetNodeHeight() (uint64, error) {
+	return palette.GetNodeHeight()
+}
+
+func (s *paletteSource) GetProof(contractAddress string, key string, blockHeight string) ([]byte, error) {
+	return palette.GetProof(contractAddress, key, blockHeight)
+}
+
+func (s *paletteSource) FilterCrossChainEvent(start, end uint64) (
+	[]*eccm_abi.EthCrossChainManagerCrossChainEvent, error) {
+
+	opt := &bind.FilterOpts{
+		Start:   start,
+		End:     &end,
+		Context: context.Background(),
+	}
+	iter, err := s.eccm.FilterCrossChainEvent(opt, nil)
+	if err != nil {
+		return nil, err
+	}
+	if iter == nil {
+		return nil, nil
+	}
+	defer iter.Close()
+
+	list := make([]*eccm_abi.EthCrossChainManagerCrossChainEvent, 0)
+	for iter.Next() {
+		list = append(list, iter.Event)
+	}
+	return list, iter.Error()
+}
+
+// polyClient implements `PolyClient` with poly sdk, native contract invocations are
+// flattened into the interface.
+type polyClient struct {
+	*polysdk.PolySdk
+}
+
+func (c *polyClient) SyncBlockHeader(
+	chainId uint64,
+	address polycm.Address,
+	headers [][]byte,
+	signer *polysdk.Account,
+) (polycm.Uint256, error) {
+	return c.Native.Hs.SyncBlockHeader(chainId, address, headers, signer)
+}
+
+func (c *polyClient) ImportOuterTransfer(
+	sourceChainId uint64,
+	txData []byte,
+	height uint32,
+	proof []byte,
+	relayerAddress []byte,
+	HeaderOrCrossChainMsg []byte,
+	signer *polysdk.Account,
+) (polycm.Uint256, error) {
+	return c.Native.Ccm.ImportOuterTransfer(sourceChainId, txData, height, proof, relayerAddress, HeaderOrCrossChainMsg, signer)
+}
@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitMined wait until `num` palette transactions mined.
+func waitMined(t *testing.T, env *fakeEnv, num int) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) && len(env.palette.Transactions()) < num {
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.Equal(t, num, len(env.palette.Transactions()))
+}
+
+func TestFakePolyToPaletteConfirmations(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.PaletteConfig.Confirmations = 3
+	mgr := env.polyManager(t)
+	sender := mgr.senders[0]
+
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	waitMined(t, env, 1)
+	tx := env.palette.Transactions()[0]
+
+	// the transaction is not final until it's confirmed by enough blocks.
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, 1, countPaletteTxs(t, env.db))
+	receipt, err := env.palette.TransactionReceipt(sender.ctx, tx.Hash())
+	require.NoError(t, err)
+	assert.False(t, sender.receiptConfirmed("", receipt))
+
+	env.palette.Mine(2)
+	assert.True(t, sender.receiptConfirmed("", receipt))
+	mgr.Stop()
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+
+	// the receipt of orphaned block is not confirmed.
+	env.palette.Reorg(3)
+	env.palette.Mine(5)
+	assert.False(t, sender.receiptConfirmed("", receipt))
+}
+
+func TestFakePolyToPaletteConfirmTimeout(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.PaletteConfig.ConfirmTimeout = 1
+	env.cfg.PaletteConfig.MaxGasPrice = 1
+	mgr := env.polyManager(t)
+	sender := mgr.senders[0]
+
+	// skip a nonce, so that the transaction is pending forever.
+	sender.nonceManager.UseNonce(sender.acc.Address)
+	raw := types.NewTransaction(sender.nonceManager.UseNonce(sender.acc.Address), fakeECCMContract, big.NewInt(0),
+		paletteGasLimit, big.NewInt(0), nil)
+	tx, err := sender.keyStore.SignTransaction(raw, sender.acc)
+	require.NoError(t, err)
+	require.NoError(t, env.palette.SendTransaction(sender.ctx, tx, bind.PrivateTxArgs{}))
+
+	start := time.Now()
+	_, err = sender.waitTransactionConfirm("", tx)
+	assert.ErrorIs(t, err, ErrTxConfirmTimeout)
+	assert.Less(t, int64(time.Since(start)), int64(3*time.Second))
+
+	// the persisted transaction is kept for replaying once gas price reaches the ceiling.
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	mgr.Stop()
+	assert.Equal(t, 1, countPaletteTxs(t, env.db))
+}
+
+func TestFakePolyToPaletteConfirmTimeoutNonceReturned(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.PaletteConfig.ConfirmTimeout = 1
+	env.cfg.PaletteConfig.GasPrice = 50
+	env.palette.MinGasPrice = big.NewInt(100)
+	mgr := env.polyManager(t)
+	sender := mgr.senders[0]
+
+	// nonce 1 is held by another transaction, and the relayed transaction takes nonce 0.
+	gap := sender.nonceManager.UseNonce(sender.acc.Address)
+	sender.nonceManager.UseNonce(sender.acc.Address)
+	sender.nonceManager.ReturnNonce(sender.acc.Address, gap)
+
+	// the transaction underpriced is never mined, its nonce is returned once timed out.
+	_, err := sender.sendTxToPalette(fakeECCMContract, "", nil, nil)
+	assert.ErrorIs(t, err, ErrTxConfirmTimeout)
+	assert.Empty(t, env.palette.Transactions())
+	assert.Equal(t, []uint64{gap}, sender.nonceManager.Gaps(sender.acc.Address))
+
+	// the gap filler replaces it with a self-transfer.
+	env.palette.MinGasPrice = nil
+	sender.fillNonceGaps(0)
+	mgr.Stop()
+	txs := env.palette.Transactions()
+	require.Equal(t, 1, len(txs))
+	assert.Equal(t, gap, txs[0].Nonce())
+	assert.Equal(t, sender.acc.Address, *txs[0].To())
+	assert.Empty(t, sender.nonceManager.Gaps(sender.acc.Address))
+}
+
+func TestFakePolyToPaletteNonceResync(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.RetryInterval = 1
+	mgr := env.polyManager(t)
+	sender := mgr.senders[0]
+
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	waitMined(t, env, 1)
+
+	// transaction sent by others with the same key desyncs nonce manager.
+	raw := types.NewTransaction(1, fakeECCMContract, big.NewInt(0), paletteGasLimit, big.NewInt(0), nil)
+	tx, err := sender.keyStore.SignTransaction(raw, sender.acc)
+	require.NoError(t, err)
+	require.NoError(t, env.palette.SendTransaction(sender.ctx, tx, bind.PrivateTxArgs{}))
+
+	// the rejected nonce is dropped, nonce manager resyncs from chain and the transaction is resent.
+	env.emitPolyDeposit(11, 2)
+	env.emitPolyDeposit(12, 3)
+	require.True(t, mgr.handleDepositEvents(11))
+	require.True(t, mgr.handleDepositEvents(12))
+	waitMined(t, env, 4)
+	assert.Equal(t, uint64(2), env.palette.Transactions()[2].Nonce())
+	assert.Equal(t, uint64(3), env.palette.Transactions()[3].Nonce())
+	mgr.Stop()
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+
+	// nonce state is restored after restarted.
+	mgr = env.polyManager(t)
+	sender = mgr.senders[0]
+	assert.Equal(t, uint64(4), sender.nonceManager.UseNonce(sender.acc.Address))
+	mgr.Stop()
+}
+
+func TestFakePolyToPaletteFillNonceGap(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.PaletteConfig.NonceGapTimeout = 1
+	mgr := env.polyManager(t)
+	sender := mgr.senders[0]
+
+	// nonce 0 is returned after nonce 1 broadcast, so that the relayed transaction is blocked.
+	gap := sender.nonceManager.UseNonce(sender.acc.Address)
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	time.Sleep(250 * time.Millisecond)
+	assert.Empty(t, env.palette.Transactions())
+	sender.nonceManager.ReturnNonce(sender.acc.Address, gap)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr.Start(ctx)
+	waitMined(t, env, 2)
+	cancel()
+	mgr.Stop()
+
+	filler := env.palette.Transactions()[0]
+	assert.Equal(t, gap, filler.Nonce())
+	assert.Equal(t, sender.acc.Address, *filler.To())
+	assert.Equal(t, int64(0), filler.Value().Int64())
+	assert.Empty(t, sender.nonceManager.Gaps(sender.acc.Address))
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
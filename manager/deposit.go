@@ -0,0 +1,202 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/palettechain/palette-relayer/log"
+	polycm "github.com/polynetwork/poly/common"
+)
+
+// depositItem is a cross transfer waiting in deposit queue, `key` is the serialized cross transfer which
+// is also the key of `retry` bucket, and `due` is the unix time after which the failed transfer is retried.
+type depositItem struct {
+	key     []byte
+	height  uint64
+	due     int64
+	removed bool
+}
+
+type depositHeap struct {
+	items []*depositItem
+	less  func(a, b *depositItem) bool
+}
+
+func (h *depositHeap) Len() int           { return len(h.items) }
+func (h *depositHeap) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *depositHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *depositHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(*depositItem))
+}
+
+func (h *depositHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	return item
+}
+
+// peek return the first item which is not removed, and nil if the heap is empty.
+func (h *depositHeap) peek() *depositItem {
+	for h.Len() > 0 {
+		if item := h.items[0]; !item.removed {
+			return item
+		}
+		heap.Pop(h)
+	}
+	return nil
+}
+
+// depositQueue is the in-memory index of `retry` bucket, which is rebuilt from the bucket on startup.
+// new cross transfers wait in `waiting` ordered by height until the reference height passes their safe
+// distance, and failed ones wait in `delayed` ordered by due time until their backoff expires, so that
+// each cross transfer is only considered when it's ready.
+type depositQueue struct {
+	mtx     sync.Mutex
+	waiting *depositHeap
+	delayed *depositHeap
+	queued  map[string]*depositItem
+}
+
+func newDepositQueue() *depositQueue {
+	return &depositQueue{
+		waiting: &depositHeap{less: func(a, b *depositItem) bool {
+			return a.height < b.height
+		}},
+		delayed: &depositHeap{less: func(a, b *depositItem) bool {
+			return a.due < b.due
+		}},
+		queued: make(map[string]*depositItem),
+	}
+}
+
+// push queue the new cross transfer emitted in block at `height`, it's ignored if already queued.
+func (q *depositQueue) push(key []byte, height uint64) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if _, ok := q.queued[string(key)]; ok {
+		return
+	}
+	item := &depositItem{key: key, height: height}
+	q.queued[string(key)] = item
+	heap.Push(q.waiting, item)
+}
+
+// delay queue the failed cross transfer until `due`, it replaces the queued one with the same key.
+func (q *depositQueue) delay(key []byte, height uint64, due int64) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.remove(key)
+	item := &depositItem{key: key, height: height, due: due}
+	q.queued[string(key)] = item
+	heap.Push(q.delayed, item)
+}
+
+// popMature pop new cross transfers whose height + `distance` is lower than `refHeight`.
+func (q *depositQueue) popMature(refHeight, distance uint64) []*depositItem {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	list := make([]*depositItem, 0)
+	for item := q.waiting.peek(); item != nil && item.height+distance < refHeight; item = q.waiting.peek() {
+		heap.Pop(q.waiting)
+		delete(q.queued, string(item.key))
+		list = append(list, item)
+	}
+	return list
+}
+
+// popDue pop failed cross transfers whose backoff expired before `now`.
+func (q *depositQueue) popDue(now int64) []*depositItem {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	list := make([]*depositItem, 0)
+	for item := q.delayed.peek(); item != nil && item.due <= now; item = q.delayed.peek() {
+		heap.Pop(q.delayed)
+		delete(q.queued, string(item.key))
+		list = append(list, item)
+	}
+	return list
+}
+
+// drop remove the cross transfer from queue if it's queued.
+func (q *depositQueue) drop(key []byte) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.remove(key)
+}
+
+func (q *depositQueue) len() int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return len(q.queued)
+}
+
+// remove mark the queued item removed, and it's discarded when reaching the top of heap.
+func (q *depositQueue) remove(key []byte) {
+	if item, ok := q.queued[string(key)]; ok {
+		item.removed = true
+		delete(q.queued, string(key))
+	}
+}
+
+// loadDeposits rebuild deposit queue from `retry` bucket, cross transfers which failed before are
+// queued until their backoff expires, and the others wait for the safe distance.
+func (m *PaletteManager) loadDeposits() error {
+	err := m.db.ForEachRetry(func(k, raw []byte) {
+		crossTx, err := deserializeCrossTransfer(k)
+		if err != nil {
+			log.Errorf("PaletteManager loadDeposits - deserialize cross transfer %x err: %s", k, err)
+			return
+		}
+		if raw == nil {
+			m.deposits.push(k, crossTx.height)
+			return
+		}
+		state := new(RetryState)
+		if err := state.Deserialization(polycm.NewZeroCopySource(raw)); err != nil {
+			log.Errorf("PaletteManager loadDeposits - deserialize retry state of tx %s err: %s",
+				txIdHex(crossTx.txId), err)
+		}
+		m.deposits.delay(k, crossTx.height, state.nextTime)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("PaletteManager loadDeposits - %d cross transfers queued", m.deposits.len())
+	return nil
+}
+
+// requeueDeposit queue the failed cross transfer again until `due`.
+func (m *PaletteManager) requeueDeposit(v []byte, due int64) {
+	crossTx, err := deserializeCrossTransfer(v)
+	if err != nil {
+		log.Errorf("PaletteManager requeueDeposit - deserialize cross transfer err: %s", err)
+		return
+	}
+	m.deposits.delay(v, crossTx.height, due)
+}
@@ -0,0 +1,99 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func depositKeys(items []*depositItem) []string {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, string(item.key))
+	}
+	return keys
+}
+
+func TestDepositQueue(t *testing.T) {
+	q := newDepositQueue()
+	q.push([]byte("c"), 12)
+	q.push([]byte("a"), 10)
+	q.push([]byte("b"), 11)
+	q.push([]byte("a"), 10)
+	assert.Equal(t, 3, q.len())
+
+	// cross transfers are popped in order of height once they reach the safe distance.
+	assert.Equal(t, 0, len(q.popMature(12, 2)))
+	assert.Equal(t, []string{"a"}, depositKeys(q.popMature(13, 2)))
+	assert.Equal(t, []string{"b", "c"}, depositKeys(q.popMature(20, 2)))
+	assert.Equal(t, 0, q.len())
+
+	// delayed cross transfers are popped in order of due time.
+	q.delay([]byte("a"), 10, 200)
+	q.delay([]byte("b"), 11, 100)
+	q.delay([]byte("b"), 11, 300)
+	assert.Equal(t, 2, q.len())
+	assert.Equal(t, 0, len(q.popDue(199)))
+	assert.Equal(t, []string{"a", "b"}, depositKeys(q.popDue(300)))
+
+	// removed cross transfers are never popped.
+	q.push([]byte("a"), 10)
+	q.delay([]byte("b"), 11, 100)
+	q.drop([]byte("a"))
+	q.drop([]byte("b"))
+	assert.Equal(t, 0, q.len())
+	assert.Equal(t, 0, len(q.popMature(20, 2)))
+	assert.Equal(t, 0, len(q.popDue(300)))
+
+	// delaying moves the waiting cross transfer.
+	q.push([]byte("a"), 10)
+	q.delay([]byte("a"), 10, 100)
+	assert.Equal(t, 0, len(q.popMature(20, 2)))
+	assert.Equal(t, []string{"a"}, depositKeys(q.popDue(100)))
+}
+
+func TestFakePaletteDepositQueue(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+	distance := mgr.safeBlockDistance()
+
+	calls := 0
+	env.poly.ImportOuterTransferHook = func(_ *fake.ImportedTransfer) error {
+		calls++
+		return nil
+	}
+
+	env.palette.Mine(1)
+	h1, _ := env.emitLockEvent(1)
+	env.palette.Mine(1)
+	h2, _ := env.emitLockEvent(2)
+	env.palette.Mine(int(distance) + 1)
+	refHeight := syncPalette(t, mgr)
+	require.Equal(t, 2, mgr.deposits.len())
+
+	// each cross transfer is handled exactly when reference height passes its safe distance.
+	for height := h1; height <= refHeight; height++ {
+		require.NoError(t, mgr.handleDepositEvents(height))
+		switch {
+		case height <= h1+distance:
+			assert.Equal(t, 0, calls)
+		case height <= h2+distance:
+			assert.Equal(t, 1, calls)
+		default:
+			assert.Equal(t, 2, calls)
+		}
+	}
+	assert.Equal(t, 0, mgr.deposits.len())
+
+	// queue is rebuilt from `retry` bucket on restart.
+	env.emitLockEvent(3)
+	env.palette.Mine(int(distance) + 1)
+	refHeight = syncPalette(t, mgr)
+	restarted := env.paletteManager(t)
+	assert.Equal(t, 1, restarted.deposits.len())
+	require.NoError(t, restarted.handleDepositEvents(refHeight))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 0, restarted.deposits.len())
+}
@@ -0,0 +1,126 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// ErrorCategory is the kind of failures returned by poly and palette rpc, and
+// relayer decides to retry, delete or alert by the category.
+type ErrorCategory int
+
+const (
+	ErrCategoryUnknown ErrorCategory = iota
+	ErrCategoryAlreadyDone
+	ErrCategoryInsufficientFunds
+	ErrCategoryInvalidProof
+	ErrCategoryHeaderNotSynced
+	ErrCategoryTransientNetwork
+)
+
+func (c ErrorCategory) String() string {
+	switch c {
+	case ErrCategoryAlreadyDone:
+		return "already-done"
+	case ErrCategoryInsufficientFunds:
+		return "insufficient-funds"
+	case ErrCategoryInvalidProof:
+		return "invalid-proof"
+	case ErrCategoryHeaderNotSynced:
+		return "header-not-synced"
+	case ErrCategoryTransientNetwork:
+		return "transient-network"
+	default:
+		return "unknown"
+	}
+}
+
+// errorPatterns is the table of known error messages, it is matched in order and case insensitively,
+// so that more specific patterns should be placed before the general ones.
+var errorPatterns = []struct {
+	category ErrorCategory
+	patterns []string
+}{
+	{ErrCategoryAlreadyDone, []string{
+		"tx already done",
+		"the transaction has been executed",
+	}},
+	{ErrCategoryInsufficientFunds, []string{
+		"current utxo is not enough",
+		"insufficient funds",
+		"insufficient balance",
+		"balance is not enough",
+	}},
+	{ErrCategoryHeaderNotSynced, []string{
+		"header not synced",
+		"header not exist",
+		"can not find header",
+		"get header by height",
+		"height is too big",
+		"header not found",
+	}},
+	{ErrCategoryInvalidProof, []string{
+		"invalid proof",
+		"verify proof",
+		"verifymerkleproof",
+		"verify signature",
+	}},
+	{ErrCategoryTransientNetwork, []string{
+		"connection refused",
+		"connection reset",
+		"broken pipe",
+		"i/o timeout",
+		"timeout",
+		"no such host",
+		"eof",
+		"too many requests",
+		"service unavailable",
+		"bad gateway",
+		"temporarily unavailable",
+		// the transaction is in mempool of node, which proves nothing about poly chain.
+		"already known",
+	}},
+}
+
+// ClassifyError map poly and palette rpc errors to the error category.
+func ClassifyError(err error) ErrorCategory {
+	if err == nil {
+		return ErrCategoryUnknown
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, v := range errorPatterns {
+		for _, pattern := range v.patterns {
+			if strings.Contains(msg, pattern) {
+				return v.category
+			}
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrCategoryTransientNetwork
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrCategoryTransientNetwork
+	}
+	return ErrCategoryUnknown
+}
@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err    error
+		expect ErrorCategory
+	}{
+		{nil, ErrCategoryUnknown},
+		{fmt.Errorf("invokeNativeContract error: [ImportExTransfer] tx already done"), ErrCategoryAlreadyDone},
+		{fmt.Errorf("execution reverted: the transaction has been executed!"), ErrCategoryAlreadyDone},
+		{fmt.Errorf("already known"), ErrCategoryTransientNetwork},
+		{fmt.Errorf("chooseUtxos, current utxo is not enough"), ErrCategoryInsufficientFunds},
+		{fmt.Errorf("insufficient funds for gas * price + value"), ErrCategoryInsufficientFunds},
+		{fmt.Errorf("[VerifyFromEthProof], verifyMerkleProof failed"), ErrCategoryInvalidProof},
+		{fmt.Errorf("verify signature failed"), ErrCategoryInvalidProof},
+		{fmt.Errorf("invalid proof format"), ErrCategoryInvalidProof},
+		{fmt.Errorf("invalid proof of tx 01 on height 10, err: verify account proof err: missing node"),
+			ErrCategoryInvalidProof},
+		{fmt.Errorf("GetProof: send request err: Post http://127.0.0.1:22000: dial tcp: connection refused"),
+			ErrCategoryTransientNetwork},
+		{fmt.Errorf("GetProof, unmarshal resp err: missing trie node"), ErrCategoryUnknown},
+		{fmt.Errorf("GetHeaderByHeight, height is too big"), ErrCategoryHeaderNotSynced},
+		{fmt.Errorf("can not find header by height 100"), ErrCategoryHeaderNotSynced},
+		{fmt.Errorf("get header by height 100 failed, header not exist"), ErrCategoryHeaderNotSynced},
+		{fmt.Errorf("dial tcp 127.0.0.1:20336: connect: connection refused"), ErrCategoryTransientNetwork},
+		{fmt.Errorf("read tcp: i/o timeout"), ErrCategoryTransientNetwork},
+		{fmt.Errorf("Post http://127.0.0.1:22000: EOF"), ErrCategoryTransientNetwork},
+		{fmt.Errorf("429 Too Many Requests"), ErrCategoryTransientNetwork},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), ErrCategoryTransientNetwork},
+		{&net.DNSError{Err: "server misbehaving", Name: "node"}, ErrCategoryTransientNetwork},
+		{fmt.Errorf("execution reverted"), ErrCategoryUnknown},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expect, ClassifyError(c.err), "%v", c.err)
+	}
+}
+
+func TestErrorCategoryString(t *testing.T) {
+	assert.Equal(t, "already-done", ErrCategoryAlreadyDone.String())
+	assert.Equal(t, "insufficient-funds", ErrCategoryInsufficientFunds.String())
+	assert.Equal(t, "invalid-proof", ErrCategoryInvalidProof.String())
+	assert.Equal(t, "header-not-synced", ErrCategoryHeaderNotSynced.String())
+	assert.Equal(t, "transient-network", ErrCategoryTransientNetwork.String())
+	assert.Equal(t, "unknown", ErrCategoryUnknown.String())
+}
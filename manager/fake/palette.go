@@ -0,0 +1,667 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package fake provides deterministic in-memory palette and poly chains which implement the
+// chain access interfaces of package manager, so that relay scenarios can run in `go test`
+// without any palette or poly node.
+package fake
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/polynetwork/eth-contracts/go_abi/eccm_abi"
+)
+
+const defaultGasLimit uint64 = 100000
+
+// PaletteChain is an in-memory palette chain. Blocks are only produced by `Mine`, `EmitCrossChainEvent`
+// and transactions accepted by `SendTransaction`, each of them packs exactly one new block, so the
+// chain evolves deterministically with the calls made against it.
+type PaletteChain struct {
+	mtx *sync.Mutex
+
+	chainID    *big.Int
+	validators []common.Address
+	keys       map[common.Address]*ecdsa.PrivateKey
+	headers    []*types.Header
+	fork       uint64
+	events     map[uint64][]*eccm_abi.EthCrossChainManagerCrossChainEvent
+	proofs     map[string][]byte
+
+	eccd  common.Address
+	slots map[common.Hash]*storageSlot
+
+	txs      map[common.Hash]*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+	pool     map[common.Address]map[uint64]*types.Transaction
+	nonces   map[common.Address]uint64
+	balances map[common.Address]*big.Int
+	mined    []*types.Transaction
+
+	epochStartHeight uint32
+	epochPubKeys     []byte
+	relayed          map[uint64]map[[32]byte]bool
+
+	// SealHook is invoked before a new block committed, it returns the keys which really commit the block
+	// from the validators of parent block.
+	SealHook func(number uint64, committers []*ecdsa.PrivateKey) []*ecdsa.PrivateKey
+
+	// HeaderHook is invoked before a header returned by `HeaderByNumber`, a non-nil error fails the request.
+	HeaderHook func(number uint64) error
+
+	// SendTxHook is invoked before a transaction enters the pool, a non-nil error rejects it.
+	SendTxHook func(tx *types.Transaction) error
+
+	// EstimateGasHook is invoked before gas estimated, a non-nil error fails the estimation.
+	EstimateGasHook func(msg ethereum.CallMsg) error
+
+	// MaxFilterRange limits the number of blocks queried by `FilterCrossChainEvent`, zero means no limit.
+	MaxFilterRange uint64
+
+	// GasLimit returned by `EstimateGas`.
+	GasLimit uint64
+
+	// GasPrice returned by `SuggestGasPrice`, nil means zero.
+	GasPrice *big.Int
+
+	// MinGasPrice is the lowest gas price of transactions to be packed, underpriced transactions stay pending
+	// until they are replaced. nil means no limit.
+	MinGasPrice *big.Int
+}
+
+// storageSlot is the value of ECCD contract storage written in block at `height`.
+type storageSlot struct {
+	height uint64
+	value  common.Hash
+}
+
+// NewPaletteChain create a palette chain which only contains the genesis block, and `validators` are
+// recorded in istanbul extra of blocks until `SetValidators` is called. just like istanbul consensus,
+// each block is proposed and committed by validators recorded in its parent block.
+func NewPaletteChain(chainID uint64, validators []*ecdsa.PrivateKey) *PaletteChain {
+	c := &PaletteChain{
+		mtx:      new(sync.Mutex),
+		chainID:  new(big.Int).SetUint64(chainID),
+		keys:     make(map[common.Address]*ecdsa.PrivateKey),
+		events:   make(map[uint64][]*eccm_abi.EthCrossChainManagerCrossChainEvent),
+		proofs:   make(map[string][]byte),
+		slots:    make(map[common.Hash]*storageSlot),
+		txs:      make(map[common.Hash]*types.Transaction),
+		receipts: make(map[common.Hash]*types.Receipt),
+		pool:     make(map[common.Address]map[uint64]*types.Transaction),
+		nonces:   make(map[common.Address]uint64),
+		balances: make(map[common.Address]*big.Int),
+		relayed:  make(map[uint64]map[[32]byte]bool),
+		GasLimit: defaultGasLimit,
+	}
+	c.setValidators(validators)
+	c.headers = []*types.Header{c.newHeader(nil)}
+	return c
+}
+
+// Mine pack `n` empty blocks and return the latest block height.
+func (c *PaletteChain) Mine(n int) uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for i := 0; i < n; i++ {
+		c.mine()
+	}
+	return c.height()
+}
+
+// SetCrossChainData settle the ECCD contract, and subsequent cross chain events are recorded in its storage
+// just like ECCM contract does, the state root of blocks and `GetProof` results are built from that storage.
+func (c *PaletteChain) SetCrossChainData(eccd common.Address) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.eccd = eccd
+}
+
+// SetValidators change the validators recorded in istanbul extra of subsequent blocks.
+func (c *PaletteChain) SetValidators(validators []*ecdsa.PrivateKey) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.setValidators(validators)
+}
+
+func (c *PaletteChain) setValidators(validators []*ecdsa.PrivateKey) {
+	c.validators = make([]common.Address, 0, len(validators))
+	for _, key := range validators {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		c.keys[addr] = key
+		c.validators = append(c.validators, addr)
+	}
+}
+
+// EmitCrossChainEvent pack a new block which contains the cross chain event and return its height.
+// block number, block hash and tx hash of `evt.Raw` are filled by the chain if they are empty.
+func (c *PaletteChain) EmitCrossChainEvent(evt *eccm_abi.EthCrossChainManagerCrossChainEvent) uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.eccd != (common.Address{}) {
+		c.slots[txHashSlot(evt.TxId)] = &storageSlot{
+			height: c.height() + 1,
+			value:  crypto.Keccak256Hash(evt.Rawdata),
+		}
+	}
+	hdr := c.mine()
+	height := hdr.Number.Uint64()
+	evt.Raw.BlockNumber = height
+	evt.Raw.BlockHash = hdr.Hash()
+	if evt.Raw.TxHash == (common.Hash{}) {
+		evt.Raw.TxHash = crypto.Keccak256Hash(hdr.Hash().Bytes(), evt.TxId)
+	}
+	c.events[height] = append(c.events[height], evt)
+	return height
+}
+
+// Reorg drop the latest `depth` blocks together with their events, subsequent blocks are built on
+// a new fork so that they never share hashes with the dropped ones. it returns the new block height.
+func (c *PaletteChain) Reorg(depth int) uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if depth > len(c.headers)-1 {
+		depth = len(c.headers) - 1
+	}
+	for i := 0; i < depth; i++ {
+		delete(c.events, c.height())
+		c.headers = c.headers[:len(c.headers)-1]
+	}
+	for key, slot := range c.slots {
+		if slot.height > c.height() {
+			delete(c.slots, key)
+		}
+	}
+	c.fork++
+	return c.height()
+}
+
+// SetBalance settle the balance of account, and balances of other accounts are zero.
+func (c *PaletteChain) SetBalance(account common.Address, balance *big.Int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.balances[account] = new(big.Int).Set(balance)
+}
+
+// SetProof register the raw `eth_getProof` result for the storage key of contract at block height,
+// which is the hex string formatted by `hexutil.EncodeBig`.
+func (c *PaletteChain) SetProof(contractAddress string, key string, blockHeight string, proof []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.proofs[proofKey(contractAddress, key, blockHeight)] = proof
+}
+
+// SetEpoch settle the poly epoch recorded in palette ECCD contract.
+func (c *PaletteChain) SetEpoch(startHeight uint32, pubKeys []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.epochStartHeight = startHeight
+	c.epochPubKeys = common.CopyBytes(pubKeys)
+}
+
+// MarkRelayed record the poly transaction as executed in palette ECCD contract.
+func (c *PaletteChain) MarkRelayed(fromChainID uint64, fromChainTx [32]byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if _, ok := c.relayed[fromChainID]; !ok {
+		c.relayed[fromChainID] = make(map[[32]byte]bool)
+	}
+	c.relayed[fromChainID][fromChainTx] = true
+}
+
+// Transactions return all of mined transactions in order.
+func (c *PaletteChain) Transactions() []*types.Transaction {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	list := make([]*types.Transaction, len(c.mined))
+	copy(list, c.mined)
+	return list
+}
+
+func (c *PaletteChain) ChainID(_ context.Context) (*big.Int, error) {
+	return new(big.Int).Set(c.chainID), nil
+}
+
+func (c *PaletteChain) GetNodeHeight() (uint64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.height(), nil
+}
+
+func (c *PaletteChain) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	hdr, err := c.header(number)
+	if err != nil {
+		return nil, err
+	}
+	if c.HeaderHook != nil {
+		if err := c.HeaderHook(hdr.Number.Uint64()); err != nil {
+			return nil, err
+		}
+	}
+	return types.CopyHeader(hdr), nil
+}
+
+func (c *PaletteChain) BlockByNumber(_ context.Context, number *big.Int) (*types.Block, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	hdr, err := c.header(number)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(hdr), nil
+}
+
+func (c *PaletteChain) FilterCrossChainEvent(start, end uint64) (
+	[]*eccm_abi.EthCrossChainManagerCrossChainEvent, error) {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if start > end {
+		return nil, fmt.Errorf("invalid block range [%d, %d]", start, end)
+	}
+	if c.MaxFilterRange > 0 && end-start+1 > c.MaxFilterRange {
+		return nil, fmt.Errorf("block range [%d, %d] too large, limit is %d", start, end, c.MaxFilterRange)
+	}
+	list := make([]*eccm_abi.EthCrossChainManagerCrossChainEvent, 0)
+	for h := start; h <= end && h <= c.height(); h++ {
+		list = append(list, c.events[h]...)
+	}
+	return list, nil
+}
+
+// GetProof return the proof registered by `SetProof`, or the merkle proof of ECCD contract storage
+// if the contract is settled, otherwise an empty proof which only contains the requested contract
+// address and storage key.
+func (c *PaletteChain) GetProof(contractAddress string, key string, blockHeight string) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if proof, ok := c.proofs[proofKey(contractAddress, key, blockHeight)]; ok {
+		return common.CopyBytes(proof), nil
+	}
+	if c.eccd != (common.Address{}) && common.HexToAddress(contractAddress) == c.eccd {
+		height, err := hexutil.DecodeUint64(blockHeight)
+		if err != nil {
+			return nil, err
+		}
+		return c.storageProof(common.HexToHash(key), height)
+	}
+	return json.Marshal(map[string]interface{}{
+		"address":      contractAddress,
+		"accountProof": []string{},
+		"storageProof": []map[string]interface{}{{"key": key, "value": "0x0", "proof": []string{}}},
+	})
+}
+
+func (c *PaletteChain) PendingNonceAt(_ context.Context, account common.Address) (uint64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.nonces[account], nil
+}
+
+func (c *PaletteChain) BalanceAt(_ context.Context, account common.Address, _ *big.Int) (*big.Int, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if balance, ok := c.balances[account]; ok {
+		return new(big.Int).Set(balance), nil
+	}
+	return new(big.Int), nil
+}
+
+func (c *PaletteChain) SuggestGasPrice(_ context.Context) (*big.Int, error) {
+	if c.GasPrice == nil {
+		return new(big.Int), nil
+	}
+	return new(big.Int).Set(c.GasPrice), nil
+}
+
+func (c *PaletteChain) EstimateGas(_ context.Context, msg ethereum.CallMsg) (uint64, error) {
+	if c.EstimateGasHook != nil {
+		if err := c.EstimateGasHook(msg); err != nil {
+			return 0, err
+		}
+	}
+	return c.GasLimit, nil
+}
+
+// SendTransaction accept transactions whose nonce is not lower than the account's next nonce, and the
+// pending transaction with the same nonce is replaced. transactions are packed as soon as there is
+// no nonce gap or underpriced transaction before them, otherwise they stay pending.
+func (c *PaletteChain) SendTransaction(_ context.Context, tx *types.Transaction, _ bind.PrivateTxArgs) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.SendTxHook != nil {
+		if err := c.SendTxHook(tx); err != nil {
+			return err
+		}
+	}
+
+	from, err := types.Sender(types.NewEIP155Signer(c.chainID), tx)
+	if err != nil {
+		return err
+	}
+	if tx.Nonce() < c.nonces[from] {
+		return fmt.Errorf("nonce too low")
+	}
+	if _, ok := c.pool[from]; !ok {
+		c.pool[from] = make(map[uint64]*types.Transaction)
+	}
+	c.pool[from][tx.Nonce()] = tx
+	c.txs[tx.Hash()] = tx
+
+	for {
+		next, ok := c.pool[from][c.nonces[from]]
+		if !ok || (c.MinGasPrice != nil && next.GasPrice().Cmp(c.MinGasPrice) < 0) {
+			break
+		}
+		delete(c.pool[from], c.nonces[from])
+		c.nonces[from]++
+		c.packTx(next)
+	}
+	return nil
+}
+
+func (c *PaletteChain) TransactionByHash(_ context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	tx, ok := c.txs[hash]
+	if !ok {
+		return nil, false, ethereum.NotFound
+	}
+	_, mined := c.receipts[hash]
+	return tx, !mined, nil
+}
+
+func (c *PaletteChain) TransactionReceipt(_ context.Context, txHash common.Hash) (*types.Receipt, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	receipt, ok := c.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	cpy := *receipt
+	return &cpy, nil
+}
+
+func (c *PaletteChain) GetCurEpochStartHeight(_ *bind.CallOpts) (uint32, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.epochStartHeight, nil
+}
+
+func (c *PaletteChain) GetCurEpochConPubKeyBytes(_ *bind.CallOpts) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return common.CopyBytes(c.epochPubKeys), nil
+}
+
+func (c *PaletteChain) CheckIfFromChainTxExist(_ *bind.CallOpts, fromChainId uint64, fromChainTx [32]byte) (bool, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.relayed[fromChainId][fromChainTx], nil
+}
+
+func (c *PaletteChain) height() uint64 {
+	return uint64(len(c.headers) - 1)
+}
+
+func (c *PaletteChain) header(number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return c.headers[len(c.headers)-1], nil
+	}
+	if !number.IsUint64() || number.Uint64() > c.height() {
+		return nil, ethereum.NotFound
+	}
+	return c.headers[number.Uint64()], nil
+}
+
+func (c *PaletteChain) mine() *types.Header {
+	hdr := c.newHeader(c.headers[len(c.headers)-1])
+	c.headers = append(c.headers, hdr)
+	return hdr
+}
+
+func (c *PaletteChain) packTx(tx *types.Transaction) {
+	hdr := c.mine()
+	c.mined = append(c.mined, tx)
+	c.receipts[tx.Hash()] = &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: tx.Gas(),
+		GasUsed:           tx.Gas(),
+		TxHash:            tx.Hash(),
+		BlockHash:         hdr.Hash(),
+		BlockNumber:       new(big.Int).Set(hdr.Number),
+	}
+}
+
+func (c *PaletteChain) newHeader(parent *types.Header) *types.Header {
+	var (
+		number     = big.NewInt(0)
+		parentHash common.Hash
+	)
+	if parent != nil {
+		number = new(big.Int).Add(parent.Number, common.Big1)
+		parentHash = parent.Hash()
+	}
+
+	vals := copyAddrs(c.validators)
+	sort.Slice(vals, func(i, j int) bool {
+		return vals[i].Hex() < vals[j].Hex()
+	})
+	extra := &types.IstanbulExtra{
+		Validators:    vals,
+		Seal:          []byte{},
+		CommittedSeal: [][]byte{},
+	}
+
+	hdr := &types.Header{
+		ParentHash:  parentHash,
+		UncleHash:   types.EmptyUncleHash,
+		Root:        c.stateRoot(number.Uint64()),
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  big.NewInt(1),
+		Number:      number,
+		GasLimit:    8000000,
+		Time:        number.Uint64(),
+		MixDigest:   types.IstanbulDigest,
+	}
+	c.setExtra(hdr, extra)
+
+	// validators of parent block propose and commit the block.
+	committers := c.committers(parent)
+	proposer := committers[number.Uint64()%uint64(len(committers))]
+	extra.Seal = sign(crypto.Keccak256(sigHash(hdr).Bytes()), proposer)
+	c.setExtra(hdr, extra)
+
+	if c.SealHook != nil {
+		committers = c.SealHook(number.Uint64(), committers)
+	}
+	seal := crypto.Keccak256(istanbulCore.PrepareCommittedSeal(hdr.Hash()))
+	for _, key := range committers {
+		extra.CommittedSeal = append(extra.CommittedSeal, sign(seal, key))
+	}
+	c.setExtra(hdr, extra)
+	return hdr
+}
+
+// committers return keys of validators recorded in the parent block.
+func (c *PaletteChain) committers(parent *types.Header) []*ecdsa.PrivateKey {
+	vals := c.validators
+	if parent != nil {
+		extra, _ := types.ExtractIstanbulExtra(parent)
+		vals = extra.Validators
+	}
+	keys := make([]*ecdsa.PrivateKey, 0, len(vals))
+	for _, addr := range vals {
+		keys = append(keys, c.keys[addr])
+	}
+	return keys
+}
+
+func (c *PaletteChain) setExtra(hdr *types.Header, extra *types.IstanbulExtra) {
+	payload, _ := rlp.EncodeToBytes(extra)
+	vanity := make([]byte, types.IstanbulExtraVanity)
+	binary.BigEndian.PutUint64(vanity, c.fork)
+	hdr.Extra = append(vanity, payload...)
+}
+
+// stateRoot return the state root at `height`, which only contains ECCD contract.
+func (c *PaletteChain) stateRoot(height uint64) common.Hash {
+	if c.eccd == (common.Address{}) {
+		return types.EmptyRootHash
+	}
+	_, stateTrie := c.tries(height)
+	return stateTrie.Hash()
+}
+
+// tries build storage trie of ECCD contract and the state trie at `height`.
+func (c *PaletteChain) tries(height uint64) (*trie.Trie, *trie.Trie) {
+	storageTrie, _ := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	for key, slot := range c.slots {
+		if slot.height > height {
+			continue
+		}
+		enc, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(slot.value.Bytes()))
+		storageTrie.Update(crypto.Keccak256(key.Bytes()), enc)
+	}
+
+	account, _ := rlp.EncodeToBytes(&state.Account{
+		Balance:  new(big.Int),
+		Root:     storageTrie.Hash(),
+		CodeHash: crypto.Keccak256(nil),
+	})
+	stateTrie, _ := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	stateTrie.Update(crypto.Keccak256(c.eccd.Bytes()), account)
+	return storageTrie, stateTrie
+}
+
+// storageProof build the `eth_getProof` result of ECCD contract storage `key` at `height`.
+func (c *PaletteChain) storageProof(key common.Hash, height uint64) ([]byte, error) {
+	storageTrie, stateTrie := c.tries(height)
+
+	accountProof := make(proofList, 0)
+	if err := stateTrie.Prove(crypto.Keccak256(c.eccd.Bytes()), 0, &accountProof); err != nil {
+		return nil, err
+	}
+	storageProof := make(proofList, 0)
+	if err := storageTrie.Prove(crypto.Keccak256(key.Bytes()), 0, &storageProof); err != nil {
+		return nil, err
+	}
+
+	value := big.NewInt(0)
+	if slot, ok := c.slots[key]; ok && slot.height <= height {
+		value.SetBytes(slot.value.Bytes())
+	}
+	return json.Marshal(map[string]interface{}{
+		"address":      c.eccd.Hex(),
+		"balance":      "0x0",
+		"codeHash":     crypto.Keccak256Hash(nil).Hex(),
+		"nonce":        "0x0",
+		"storageHash":  storageTrie.Hash().Hex(),
+		"accountProof": accountProof,
+		"storageProof": []map[string]interface{}{{
+			"key":   key.Hex(),
+			"value": hexutil.EncodeBig(value),
+			"proof": storageProof,
+		}},
+	})
+}
+
+// proofList collects merkle proof nodes in order, and marshals them as hex strings.
+type proofList []string
+
+func (l *proofList) Put(_ []byte, value []byte) error {
+	*l = append(*l, hexutil.Encode(value))
+	return nil
+}
+
+func (l *proofList) Delete(_ []byte) error {
+	return nil
+}
+
+// txHashSlot return the storage key of `EthTxHashMap[txId]` in ECCD contract, which is located at slot 1.
+func txHashSlot(txId []byte) common.Hash {
+	index := common.LeftPadBytes(new(big.Int).SetBytes(txId).Bytes(), 32)
+	return crypto.Keccak256Hash(index, common.LeftPadBytes([]byte{1}, 32))
+}
+
+func proofKey(contractAddress string, key string, blockHeight string) string {
+	return fmt.Sprintf("%s-%s-%s", common.HexToAddress(contractAddress).Hex(), key, blockHeight)
+}
+
+// sigHash is the hash of header signed by proposer, which excludes seals in istanbul extra.
+func sigHash(hdr *types.Header) common.Hash {
+	enc, _ := rlp.EncodeToBytes(types.IstanbulFilteredHeader(hdr, false))
+	return crypto.Keccak256Hash(enc)
+}
+
+func sign(hash []byte, key *ecdsa.PrivateKey) []byte {
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+func copyAddrs(list []common.Address) []common.Address {
+	cpy := make([]common.Address, len(list))
+	copy(cpy, list)
+	return cpy
+}
@@ -0,0 +1,334 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package fake
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	polysdk "github.com/polynetwork/poly-go-sdk"
+	sdkcom "github.com/polynetwork/poly-go-sdk/common"
+	polycm "github.com/polynetwork/poly/common"
+	polytypes "github.com/polynetwork/poly/core/types"
+)
+
+// ImportedTransfer records the arguments of an `ImportOuterTransfer` invocation.
+type ImportedTransfer struct {
+	TxHash        string
+	SourceChainID uint64
+	TxData        []byte
+	Height        uint32
+	Proof         []byte
+	Relayer       []byte
+	Header        []byte
+}
+
+// SyncedHeaders records the arguments of a `SyncBlockHeader` invocation.
+type SyncedHeaders struct {
+	TxHash  string
+	ChainID uint64
+	Headers [][]byte
+}
+
+// PolyChain is an in-memory poly chain. every accepted transaction is packed in the current block
+// and the chain height increases by one, so a transaction is confirmed once it has been sent.
+type PolyChain struct {
+	mtx *sync.Mutex
+
+	height        uint32
+	storage       map[string][]byte
+	headers       map[uint32]*polytypes.Header
+	events        map[uint32][]*sdkcom.SmartContactEvent
+	merkleProofs  map[[2]uint32]*sdkcom.MerkleProof
+	statesProofs  map[string]*sdkcom.MerkleProof
+	txHeights     map[string]uint32
+	txEvents      map[string]*sdkcom.SmartContactEvent
+	txCount       uint64
+	imported      []*ImportedTransfer
+	syncedHeaders []*SyncedHeaders
+
+	// SyncBlockHeaderHook is invoked before headers are accepted, a non-nil error rejects the transaction.
+	SyncBlockHeaderHook func(chainID uint64, headers [][]byte) error
+
+	// ImportOuterTransferHook is invoked before transfer is accepted, a non-nil error rejects the transaction.
+	ImportOuterTransferHook func(transfer *ImportedTransfer) error
+}
+
+// NewPolyChain create a poly chain whose current block height is `height`.
+func NewPolyChain(height uint32) *PolyChain {
+	return &PolyChain{
+		mtx:          new(sync.Mutex),
+		height:       height,
+		storage:      make(map[string][]byte),
+		headers:      make(map[uint32]*polytypes.Header),
+		events:       make(map[uint32][]*sdkcom.SmartContactEvent),
+		merkleProofs: make(map[[2]uint32]*sdkcom.MerkleProof),
+		statesProofs: make(map[string]*sdkcom.MerkleProof),
+		txHeights:    make(map[string]uint32),
+		txEvents:     make(map[string]*sdkcom.SmartContactEvent),
+	}
+}
+
+// Mine increase the chain height by `n` and return the current block height.
+func (c *PolyChain) Mine(n uint32) uint32 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.height += n
+	return c.height
+}
+
+// SetStorage settle the native contract storage read by `GetStorage`.
+func (c *PolyChain) SetStorage(contractAddress string, key []byte, value []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.storage[storageKey(contractAddress, key)] = common.CopyBytes(value)
+}
+
+// AddHeader record the block header at `hdr.Height`.
+func (c *PolyChain) AddHeader(hdr *polytypes.Header) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.headers[hdr.Height] = hdr
+}
+
+// AddEvent record the smart contract event in block at `height`.
+func (c *PolyChain) AddEvent(height uint32, evt *sdkcom.SmartContactEvent) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.events[height] = append(c.events[height], evt)
+}
+
+// SetMerkleProof settle the header proof of block at `blockHeight` anchored by block at `rootHeight`.
+func (c *PolyChain) SetMerkleProof(blockHeight, rootHeight uint32, proof *sdkcom.MerkleProof) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.merkleProofs[[2]uint32{blockHeight, rootHeight}] = proof
+}
+
+// SetCrossStatesProof settle the cross states proof of `key` in block at `height`.
+func (c *PolyChain) SetCrossStatesProof(height uint32, key string, proof *sdkcom.MerkleProof) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.statesProofs[statesProofKey(height, key)] = proof
+}
+
+// SetTxState change the execution state of a packed transaction, 1 means success.
+func (c *PolyChain) SetTxState(txHash string, state byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if evt, ok := c.txEvents[txHash]; ok {
+		evt.State = state
+	}
+}
+
+// DropTx forget a packed transaction, as if it was never accepted by poly chain.
+func (c *PolyChain) DropTx(txHash string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.txHeights, txHash)
+	delete(c.txEvents, txHash)
+}
+
+// ImportedTransfers return all of accepted `ImportOuterTransfer` invocations in order.
+func (c *PolyChain) ImportedTransfers() []*ImportedTransfer {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	list := make([]*ImportedTransfer, len(c.imported))
+	copy(list, c.imported)
+	return list
+}
+
+// SyncedHeaders return all of accepted `SyncBlockHeader` invocations in order.
+func (c *PolyChain) SyncedHeaders() []*SyncedHeaders {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	list := make([]*SyncedHeaders, len(c.syncedHeaders))
+	copy(list, c.syncedHeaders)
+	return list
+}
+
+func (c *PolyChain) GetStorage(contractAddress string, key []byte) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return common.CopyBytes(c.storage[storageKey(contractAddress, key)]), nil
+}
+
+func (c *PolyChain) GetCurrentBlockHeight() (uint32, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.height, nil
+}
+
+func (c *PolyChain) GetHeaderByHeight(height uint32) (*polytypes.Header, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	hdr, ok := c.headers[height]
+	if !ok {
+		return nil, fmt.Errorf("header at height %d not found", height)
+	}
+	return hdr, nil
+}
+
+func (c *PolyChain) GetBlockHeightByTxHash(txHash string) (uint32, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	height, ok := c.txHeights[txHash]
+	if !ok {
+		return 0, fmt.Errorf("unknown transaction %s", txHash)
+	}
+	return height, nil
+}
+
+// GetMerkleProof return the proof registered by `SetMerkleProof` or an empty proof.
+func (c *PolyChain) GetMerkleProof(blockHeight, rootHeight uint32) (*sdkcom.MerkleProof, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if proof, ok := c.merkleProofs[[2]uint32{blockHeight, rootHeight}]; ok {
+		return proof, nil
+	}
+	return &sdkcom.MerkleProof{Type: "MerkleProof"}, nil
+}
+
+func (c *PolyChain) GetCrossStatesProof(height uint32, key string) (*sdkcom.MerkleProof, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	proof, ok := c.statesProofs[statesProofKey(height, key)]
+	if !ok {
+		return nil, fmt.Errorf("cross states proof of key %s at height %d not found", key, height)
+	}
+	return proof, nil
+}
+
+// GetSmartContractEvent return nil without error if the transaction is unknown, just like poly node does.
+func (c *PolyChain) GetSmartContractEvent(txHash string) (*sdkcom.SmartContactEvent, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	evt, ok := c.txEvents[txHash]
+	if !ok {
+		return nil, nil
+	}
+	cpy := *evt
+	return &cpy, nil
+}
+
+func (c *PolyChain) GetSmartContractEventByBlock(height uint32) ([]*sdkcom.SmartContactEvent, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.events[height], nil
+}
+
+func (c *PolyChain) SyncBlockHeader(
+	chainId uint64,
+	_ polycm.Address,
+	headers [][]byte,
+	_ *polysdk.Account,
+) (polycm.Uint256, error) {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.SyncBlockHeaderHook != nil {
+		if err := c.SyncBlockHeaderHook(chainId, headers); err != nil {
+			return polycm.UINT256_EMPTY, err
+		}
+	}
+
+	hash := c.packTx()
+	c.syncedHeaders = append(c.syncedHeaders, &SyncedHeaders{
+		TxHash:  hash.ToHexString(),
+		ChainID: chainId,
+		Headers: headers,
+	})
+	return hash, nil
+}
+
+func (c *PolyChain) ImportOuterTransfer(
+	sourceChainId uint64,
+	txData []byte,
+	height uint32,
+	proof []byte,
+	relayerAddress []byte,
+	HeaderOrCrossChainMsg []byte,
+	_ *polysdk.Account,
+) (polycm.Uint256, error) {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	transfer := &ImportedTransfer{
+		SourceChainID: sourceChainId,
+		TxData:        txData,
+		Height:        height,
+		Proof:         proof,
+		Relayer:       relayerAddress,
+		Header:        HeaderOrCrossChainMsg,
+	}
+	if c.ImportOuterTransferHook != nil {
+		if err := c.ImportOuterTransferHook(transfer); err != nil {
+			return polycm.UINT256_EMPTY, err
+		}
+	}
+
+	hash := c.packTx()
+	transfer.TxHash = hash.ToHexString()
+	c.imported = append(c.imported, transfer)
+	return hash, nil
+}
+
+// packTx generate a deterministic tx hash, pack the transaction in current block and produce next block.
+func (c *PolyChain) packTx() polycm.Uint256 {
+	c.txCount++
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint64(raw, c.txCount)
+	hash := polycm.Uint256(sha256.Sum256(raw))
+
+	txHash := hash.ToHexString()
+	c.txHeights[txHash] = c.height
+	c.txEvents[txHash] = &sdkcom.SmartContactEvent{TxHash: txHash, State: 1}
+	c.height++
+	return hash
+}
+
+func storageKey(contractAddress string, key []byte) string {
+	return contractAddress + hex.EncodeToString(key)
+}
+
+func statesProofKey(height uint32, key string) string {
+	return fmt.Sprintf("%d-%s", height, key)
+}
@@ -0,0 +1,79 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package fake
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Key derive a deterministic secp256k1 private key from seed.
+func Key(seed uint64) *ecdsa.PrivateKey {
+	raw := make([]byte, 8)
+	binary.BigEndian.PutUint64(raw, seed)
+	key, err := crypto.ToECDSA(crypto.Keccak256(raw))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Signer signs palette transactions with in-memory private keys instead of a keystore.
+type Signer struct {
+	chainID *big.Int
+	keys    map[common.Address]*ecdsa.PrivateKey
+	accs    []accounts.Account
+}
+
+func NewSigner(chainID uint64, keys ...*ecdsa.PrivateKey) *Signer {
+	s := &Signer{
+		chainID: new(big.Int).SetUint64(chainID),
+		keys:    make(map[common.Address]*ecdsa.PrivateKey),
+	}
+	for _, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		s.keys[addr] = key
+		s.accs = append(s.accs, accounts.Account{Address: addr})
+	}
+	return s
+}
+
+// Accounts return accounts in the order of keys passed to `NewSigner`.
+func (s *Signer) Accounts() []accounts.Account {
+	list := make([]accounts.Account, len(s.accs))
+	copy(list, s.accs)
+	return list
+}
+
+func (s *Signer) SignTransaction(tx *types.Transaction, acc accounts.Account) (*types.Transaction, error) {
+	key, ok := s.keys[acc.Address]
+	if !ok {
+		return nil, fmt.Errorf("account %s not found", acc.Address.Hex())
+	}
+	return types.SignTx(tx, types.NewEIP155Signer(s.chainID), key)
+}
+
+func (s *Signer) GetChainId() uint64 {
+	return s.chainID.Uint64()
+}
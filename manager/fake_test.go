@@ -0,0 +1,762 @@
+package manager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pltcm "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/palettechain/palette-relayer/config"
+	"github.com/palettechain/palette-relayer/db"
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/polynetwork/eth-contracts/go_abi/eccm_abi"
+	polysdk "github.com/polynetwork/poly-go-sdk"
+	polysdkcm "github.com/polynetwork/poly-go-sdk/common"
+	polycm "github.com/polynetwork/poly/common"
+	polytypes "github.com/polynetwork/poly/core/types"
+	crosscm "github.com/polynetwork/poly/native/service/cross_chain_manager/common"
+	synccm "github.com/polynetwork/poly/native/service/header_sync/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ PaletteSource   = (*fake.PaletteChain)(nil)
+	_ PaletteTxClient = (*fake.PaletteChain)(nil)
+	_ CrossChainData  = (*fake.PaletteChain)(nil)
+	_ PolyClient      = (*fake.PolyChain)(nil)
+	_ TxSigner        = (*fake.Signer)(nil)
+)
+
+const (
+	fakeSideChainID     uint64 = 107
+	fakeToChainID       uint64 = 2
+	fakePolyStartHeight uint32 = 100
+
+	// fakeValidatorSeed is the seed of the first palette validator key.
+	fakeValidatorSeed = 1000
+)
+
+var (
+	fakeProxyContract = pltcm.HexToAddress("0x0000000000000000000000000000000000000103")
+	fakeECCMContract  = pltcm.HexToAddress("0x0000000000000000000000000000000000000104")
+	fakeECCDContract  = pltcm.HexToAddress("0x0000000000000000000000000000000000000105")
+)
+
+// fakeEnv is a relay scenario which runs against in-memory palette and poly chains.
+type fakeEnv struct {
+	cfg     *config.ServiceConfig
+	db      *db.BoltDB
+	palette *fake.PaletteChain
+	poly    *fake.PolyChain
+	signer  *fake.Signer
+	valset  []pltcm.Address
+	valkeys []*ecdsa.PrivateKey
+}
+
+func newFakeEnv(t *testing.T) *fakeEnv {
+	dir, err := ioutil.TempDir("", "palette-relayer")
+	require.NoError(t, err)
+	boltDB, err := db.NewBoltDB(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		boltDB.Close()
+		_ = os.RemoveAll(dir)
+	})
+
+	valkeys := make([]*ecdsa.PrivateKey, 4)
+	valset := make([]pltcm.Address, 4)
+	for i := range valset {
+		valkeys[i] = fake.Key(uint64(fakeValidatorSeed + i))
+		valset[i] = crypto.PubkeyToAddress(valkeys[i].PublicKey)
+	}
+
+	cfg := &config.ServiceConfig{
+		PolyConfig: &config.PolyConfig{
+			EntranceContractAddress: polyCrossChainMgrContract.ToHexString(),
+		},
+		PaletteConfig: &config.PaletteConfig{
+			SideChainId:         fakeSideChainID,
+			ECCMContractAddress: fakeECCMContract.Hex(),
+			ECCDContractAddress: fakeECCDContract.Hex(),
+		},
+		RoutineNum: 1,
+		TargetContracts: config.TargetContracts{
+			{fakeProxyContract: config.ChainIDArr{}},
+		},
+	}
+
+	env := &fakeEnv{
+		cfg:     cfg,
+		db:      boltDB,
+		palette: fake.NewPaletteChain(fakeSideChainID, valkeys),
+		poly:    fake.NewPolyChain(fakePolyStartHeight),
+		signer:  fake.NewSigner(fakeSideChainID, fake.Key(1)),
+		valset:  valset,
+		valkeys: valkeys,
+	}
+
+	env.palette.SetCrossChainData(fakeECCDContract)
+
+	// palette genesis header and validators already synced to poly chain.
+	keys := &PaletteManager{config: cfg}
+	height := make([]byte, 8)
+	height[0] = 1
+	env.poly.SetStorage(polyHeaderSyncContract, keys.formatStorageKey(synccm.CONSENSUS_PEER_BLOCK_HEIGHT, nil), height)
+	env.poly.SetStorage(polyHeaderSyncContract, keys.formatStorageKey(synccm.CONSENSUS_PEER, nil), valset2Bytes(valset))
+
+	return env
+}
+
+func (e *fakeEnv) paletteManager(t *testing.T) *PaletteManager {
+	signer := &polysdk.Account{Address: polycm.Address{1}}
+	mgr, err := newPaletteManager(e.cfg, 0, 0, e.palette, e.poly, signer, e.db)
+	require.NoError(t, err)
+	return mgr
+}
+
+func (e *fakeEnv) polyManager(t *testing.T) *PolyManager {
+	mgr, err := newPolyManager(e.cfg, 0, e.poly, e.palette, e.palette, e.signer, e.signer.Accounts(), e.db)
+	require.NoError(t, err)
+	return mgr
+}
+
+// emitLockEvent emit a cross chain event from the target proxy contract on palette chain.
+func (e *fakeEnv) emitLockEvent(txId byte) (uint64, *eccm_abi.EthCrossChainManagerCrossChainEvent) {
+	param := &crosscm.MakeTxParam{
+		TxHash:              []byte{txId},
+		CrossChainID:        []byte{txId},
+		FromContractAddress: fakeProxyContract.Bytes(),
+		ToChainID:           fakeToChainID,
+		ToContractAddress:   fakeProxyContract.Bytes(),
+		Method:              "unlock",
+		Args:                []byte{txId},
+	}
+	sink := polycm.NewZeroCopySink(nil)
+	param.Serialization(sink)
+
+	evt := &eccm_abi.EthCrossChainManagerCrossChainEvent{
+		TxId:                 []byte{txId},
+		ProxyOrAssetContract: fakeProxyContract,
+		ToChainId:            fakeToChainID,
+		Rawdata:              sink.Bytes(),
+	}
+	return e.palette.EmitCrossChainEvent(evt), evt
+}
+
+// syncPalette let palette manager handle all of blocks before the latest one, and
+// wait for all of epoch headers confirmed on poly chain.
+func syncPalette(t *testing.T, mgr *PaletteManager) uint64 {
+	height, err := mgr.paletteClient.GetNodeHeight()
+	require.NoError(t, err)
+	mgr.handleBlocks(context.Background(), height)
+	require.Equal(t, height, mgr.syncProgress.get())
+	for i := 0; i < 10 && (mgr.inflight != nil || len(mgr.pendingHeaders) > 0); i++ {
+		mgr.handleBlocks(context.Background(), height)
+	}
+	require.Nil(t, mgr.inflight)
+	require.Equal(t, 0, len(mgr.pendingHeaders))
+	return height
+}
+
+func TestFakePaletteToPolyRelay(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	env.palette.Mine(3)
+	height, evt := env.emitLockEvent(1)
+	env.palette.Mine(int(mgr.safeBlockDistance()) + 1)
+	refHeight := syncPalette(t, mgr)
+
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(retryList))
+	crossTx, err := deserializeCrossTransfer(retryList[0])
+	require.NoError(t, err)
+	assert.Equal(t, height, crossTx.height)
+	assert.Equal(t, evt.Raw.TxHash.Bytes(), crossTx.txId)
+
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	imported := env.poly.ImportedTransfers()
+	require.Equal(t, 1, len(imported))
+	assert.Equal(t, uint32(refHeight-1), imported[0].Height)
+	assert.Equal(t, evt.Rawdata, imported[0].TxData)
+	assert.Equal(t, fakeSideChainID, imported[0].SourceChainID)
+
+	retryList, err = env.db.GetAllRetry()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(retryList))
+	checkMap, err := env.db.GetAllCheck()
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(checkMap))
+
+	require.NoError(t, mgr.checkLockEvents())
+	checkMap, err = env.db.GetAllCheck()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(checkMap))
+}
+
+func TestFakePaletteToPolyFailedTxRetried(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	env.emitLockEvent(1)
+	env.palette.Mine(int(mgr.safeBlockDistance()) + 1)
+	refHeight := syncPalette(t, mgr)
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+
+	imported := env.poly.ImportedTransfers()
+	require.Equal(t, 1, len(imported))
+	env.poly.SetTxState(imported[0].TxHash, 0)
+
+	require.NoError(t, mgr.checkLockEvents())
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(retryList))
+}
+
+func TestFakePaletteEpochHeaderSync(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	env.palette.Mine(2)
+	env.addValidator(0x10)
+	env.palette.SetValidators(env.valkeys)
+	epochHeight := env.palette.Mine(1)
+	env.palette.Mine(1)
+	syncPalette(t, mgr)
+
+	synced := env.poly.SyncedHeaders()
+	require.Equal(t, 1, len(synced))
+	assert.Equal(t, fakeSideChainID, synced[0].ChainID)
+	assert.Equal(t, epochHeight, mgr.lastEpoch.height)
+	assert.Equal(t, len(env.valset), len(mgr.lastEpoch.valset))
+}
+
+func TestFakePaletteBatchedHeaderSync(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.HeadersPerBatch = 2
+	mgr := env.paletteManager(t)
+
+	epochs := make([]uint64, 0)
+	env.palette.Mine(1)
+	for i := 0; i < 3; i++ {
+		env.addValidator(0x10 + i)
+		env.palette.SetValidators(env.valkeys)
+		epochs = append(epochs, env.palette.Mine(1))
+		env.palette.Mine(1)
+	}
+	syncPalette(t, mgr)
+
+	synced := env.poly.SyncedHeaders()
+	require.Equal(t, 2, len(synced))
+	assert.Equal(t, 2, len(synced[0].Headers))
+	assert.Equal(t, 1, len(synced[1].Headers))
+	assert.Equal(t, 0, len(mgr.pendingHeaders))
+	assert.Equal(t, epochs[2], mgr.lastEpoch.height)
+	assert.Equal(t, len(env.valset), len(mgr.lastEpoch.valset))
+}
+
+// addValidator append a validator generated by `seed` to the validators of test environment.
+func (e *fakeEnv) addValidator(seed int) {
+	key := fake.Key(uint64(fakeValidatorSeed + seed))
+	e.valkeys = append(e.valkeys, key)
+	e.valset = append(e.valset, crypto.PubkeyToAddress(key.PublicKey))
+}
+
+// changeValidators produce an epoch block after the latest block on palette chain.
+func (e *fakeEnv) changeValidators(seed int) uint64 {
+	e.palette.Mine(1)
+	e.addValidator(seed)
+	e.palette.SetValidators(e.valkeys)
+	height := e.palette.Mine(1)
+	e.palette.Mine(1)
+	return height
+}
+
+func TestFakePaletteEpochHeaderNotCommitted(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	env.palette.Mine(1)
+	env.addValidator(0x10)
+	env.palette.SetValidators(env.valkeys)
+	env.palette.SealHook = func(_ uint64, committers []*ecdsa.PrivateKey) []*ecdsa.PrivateKey {
+		return committers[:2]
+	}
+	epochHeight := env.palette.Mine(1)
+	height := env.palette.Mine(1)
+
+	assert.False(t, mgr.handleBlockRange(context.Background(), height))
+	assert.Equal(t, epochHeight, mgr.syncProgress.get())
+	assert.Equal(t, 0, len(mgr.pendingHeaders))
+	assert.Nil(t, mgr.curHeader)
+}
+
+func TestFakePaletteManagerStop(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	// palette node keeps failing, and the manager keeps retrying until stopped.
+	env.palette.HeaderHook = func(_ uint64) error {
+		return fmt.Errorf("connection refused")
+	}
+	env.palette.Mine(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		mgr.handleBlocks(ctx, 3)
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handleBlocks not stopped")
+	}
+
+	mgr.Start(ctx)
+	mgr.Stop()
+}
+
+func TestFakePaletteHeaderCommitFailed(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	epochHeight := env.changeValidators(0x10)
+	height, err := env.palette.GetNodeHeight()
+	require.NoError(t, err)
+	mgr.handleBlocks(context.Background(), height)
+	require.NotNil(t, mgr.inflight)
+	env.poly.SetTxState(mgr.inflight.txHash, 0)
+
+	// failed commitment is resubmitted at once.
+	require.True(t, mgr.commitHeaders())
+	require.NotNil(t, mgr.inflight)
+	assert.Equal(t, 2, len(env.poly.SyncedHeaders()))
+	assert.Equal(t, mgr.inflight.txHash, env.poly.SyncedHeaders()[1].TxHash)
+
+	require.True(t, mgr.commitHeaders())
+	assert.Nil(t, mgr.inflight)
+	assert.Equal(t, epochHeight, mgr.lastEpoch.height)
+	commitMap, err := env.db.GetAllHeaderCommit()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(commitMap))
+}
+
+func TestFakePaletteHeaderCommitTimeout(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	env.changeValidators(0x10)
+	height, err := env.palette.GetNodeHeight()
+	require.NoError(t, err)
+	mgr.handleBlocks(context.Background(), height)
+	require.NotNil(t, mgr.inflight)
+	env.poly.DropTx(mgr.inflight.txHash)
+
+	// dropped transaction is waited until timeout.
+	require.False(t, mgr.commitHeaders())
+	assert.Equal(t, 1, len(env.poly.SyncedHeaders()))
+
+	mgr.inflight.submitTime -= int64(mgr.headerCommitTimeout().Seconds()) + 1
+	require.True(t, mgr.commitHeaders())
+	assert.Equal(t, 2, len(env.poly.SyncedHeaders()))
+	syncPalette(t, mgr)
+}
+
+func TestFakePaletteHeaderCommitLandedLate(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	epochHeight := env.changeValidators(0x10)
+	height, err := env.palette.GetNodeHeight()
+	require.NoError(t, err)
+	mgr.handleBlocks(context.Background(), height)
+	require.NotNil(t, mgr.inflight)
+	env.poly.DropTx(mgr.inflight.txHash)
+
+	// the commitment lands on poly chain after timeout, and synced headers are refused.
+	mgr.inflight.submitTime -= int64(mgr.headerCommitTimeout().Seconds()) + 1
+	synced := make([]byte, 8)
+	binary.LittleEndian.PutUint64(synced, epochHeight)
+	env.poly.SetStorage(polyHeaderSyncContract, mgr.formatStorageKey(synccm.CONSENSUS_PEER_BLOCK_HEIGHT, nil), synced)
+	env.poly.SyncBlockHeaderHook = func(_ uint64, _ [][]byte) error {
+		return fmt.Errorf("header already synced")
+	}
+
+	require.True(t, mgr.commitHeaders())
+	assert.Nil(t, mgr.inflight)
+	assert.Equal(t, 0, len(mgr.pendingHeaders))
+	assert.Equal(t, 1, len(env.poly.SyncedHeaders()))
+	assert.Equal(t, epochHeight, mgr.lastEpoch.height)
+	assert.Equal(t, len(env.valset), len(mgr.lastEpoch.valset))
+	syncPalette(t, mgr)
+}
+
+func TestFakePaletteHeaderCommitRestored(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	epochHeight := env.changeValidators(0x10)
+	height, err := env.palette.GetNodeHeight()
+	require.NoError(t, err)
+	mgr.handleBlocks(context.Background(), height)
+	require.NotNil(t, mgr.inflight)
+	txHash := mgr.inflight.txHash
+
+	// restart relayer before the commitment confirmed.
+	restarted := env.paletteManager(t)
+	require.NotNil(t, restarted.inflight)
+	assert.Equal(t, txHash, restarted.inflight.txHash)
+	assert.Equal(t, epochHeight, restarted.inflight.headers[0].height)
+	assert.Equal(t, len(env.valset), len(restarted.inflight.headers[0].valset))
+
+	syncPalette(t, restarted)
+	assert.Equal(t, 1, len(env.poly.SyncedHeaders()))
+	assert.Equal(t, epochHeight, restarted.lastEpoch.height)
+}
+
+func TestFakePalettePrefetch(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.PrefetchWindow = 4
+	mgr := env.paletteManager(t)
+
+	heights := make(map[uint64]bool)
+	for i := 0; i < 5; i++ {
+		env.palette.Mine(3)
+		height, _ := env.emitLockEvent(byte(i + 1))
+		heights[height] = true
+	}
+	env.palette.Mine(20)
+
+	// the first request of a block in the middle fails, and it should be fetched again in order.
+	failed := false
+	env.palette.HeaderHook = func(number uint64) error {
+		if number == 10 && !failed {
+			failed = true
+			return fmt.Errorf("connection reset")
+		}
+		return nil
+	}
+	height := syncPalette(t, mgr)
+	assert.True(t, failed)
+
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, len(heights), len(retryList))
+	for _, v := range retryList {
+		crossTx, err := deserializeCrossTransfer(v)
+		require.NoError(t, err)
+		assert.True(t, heights[crossTx.height])
+	}
+	for h := uint64(1); h < height; h++ {
+		assert.NotNil(t, env.db.GetPaletteBlockHash(h))
+	}
+	assert.Equal(t, height-1, env.db.GetPaletteHeight())
+}
+
+func TestFakePaletteRangeFilter(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.FilterBlockRange = 16
+	env.palette.MaxFilterRange = 3
+	mgr := env.paletteManager(t)
+
+	heights := make(map[uint64]bool)
+	for i := 0; i < 6; i++ {
+		env.palette.Mine(i)
+		height, _ := env.emitLockEvent(byte(i + 1))
+		heights[height] = true
+	}
+	env.palette.Mine(1)
+
+	events, err := mgr.filterCrossChainEvents(1, 30)
+	require.NoError(t, err)
+	assert.Equal(t, len(heights), len(events))
+
+	syncPalette(t, mgr)
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, len(heights), len(retryList))
+	for _, v := range retryList {
+		crossTx, err := deserializeCrossTransfer(v)
+		require.NoError(t, err)
+		assert.True(t, heights[crossTx.height])
+		delete(heights, crossTx.height)
+	}
+}
+
+func TestFakePaletteReorg(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	env.palette.Mine(3)
+	orphanedHeight, _ := env.emitLockEvent(1)
+	env.palette.Mine(2)
+	syncPalette(t, mgr)
+
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(retryList))
+
+	ancestor := env.palette.Reorg(4)
+	require.Less(t, ancestor, orphanedHeight)
+	env.palette.Mine(4)
+	height, _ := env.emitLockEvent(2)
+	env.palette.Mine(1)
+	syncPalette(t, mgr)
+
+	retryList, err = env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(retryList))
+	crossTx, err := deserializeCrossTransfer(retryList[0])
+	require.NoError(t, err)
+	assert.Equal(t, height, crossTx.height)
+
+	for h := ancestor; h < mgr.syncProgress.get(); h++ {
+		hdr, err := env.palette.HeaderByNumber(context.Background(), uint64ToBig(h))
+		require.NoError(t, err)
+		assert.Equal(t, hdr.Hash().Bytes(), env.db.GetPaletteBlockHash(h))
+	}
+}
+
+// emitPolyDeposit make a cross chain transfer to palette chain in poly block at `height`.
+func (e *fakeEnv) emitPolyDeposit(height uint32, txId byte) {
+	e.poly.AddHeader(&polytypes.Header{
+		Height:           height + 1,
+		ConsensusPayload: []byte("{}"),
+	})
+
+	merkleValue := &crosscm.ToMerkleValue{
+		TxHash:      pltcm.BytesToHash([]byte{txId}).Bytes(),
+		FromChainID: fakeToChainID,
+		MakeTxParam: &crosscm.MakeTxParam{
+			TxHash:              []byte{txId},
+			CrossChainID:        []byte{txId},
+			FromContractAddress: fakeProxyContract.Bytes(),
+			ToChainID:           fakeSideChainID,
+			ToContractAddress:   fakeProxyContract.Bytes(),
+			Method:              "unlock",
+			Args:                []byte{txId},
+		},
+	}
+	value := polycm.NewZeroCopySink(nil)
+	merkleValue.Serialization(value)
+	auditPath := polycm.NewZeroCopySink(nil)
+	auditPath.WriteVarBytes(value.Bytes())
+
+	proofKey := fmt.Sprintf("proof_key_%d", txId)
+	e.poly.SetCrossStatesProof(height, proofKey, &polysdkcm.MerkleProof{
+		Type:      "MerkleProof",
+		AuditPath: hex.EncodeToString(auditPath.Bytes()),
+	})
+	e.poly.AddEvent(height, &polysdkcm.SmartContactEvent{
+		TxHash: hex.EncodeToString(merkleValue.TxHash),
+		State:  1,
+		Notify: []*polysdkcm.NotifyEventInfo{{
+			ContractAddress: polyCrossChainMgrContract.ToHexString(),
+			States:          []interface{}{"makeProof", "", float64(fakeSideChainID), "", "", proofKey},
+		}},
+	})
+}
+
+func TestFakePolyToPaletteRelay(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.polyManager(t)
+
+	var height uint32 = 10
+	env.emitPolyDeposit(height, 1)
+	require.True(t, mgr.handleDepositEvents(height))
+
+	var txs []*fakeTxView
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if txs = minedTxs(env.palette); len(txs) > 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.Equal(t, 1, len(txs))
+	assert.Equal(t, fakeECCMContract, txs[0].to)
+	assert.Equal(t, mgr.senders[0].contractAbi.Methods["verifyHeaderAndExecuteTx"].ID(), txs[0].data[:4])
+}
+
+func TestFakePolyManagerStopDrained(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.polyManager(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr.Start(ctx)
+
+	var height uint32 = 10
+	env.emitPolyDeposit(height, 1)
+	require.True(t, mgr.handleDepositEvents(height))
+
+	// transactions in queue are sent and confirmed before stopped.
+	cancel()
+	mgr.Stop()
+	assert.Equal(t, 1, len(minedTxs(env.palette)))
+}
+
+func TestFakePolyManagerStopTimeout(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.DrainTimeout = 1
+	mgr := env.polyManager(t)
+	sender := mgr.senders[0]
+
+	// skip a nonce, so that transactions of sender are pending forever.
+	sender.nonceManager.UseNonce(sender.acc.Address)
+	sent := 0
+	env.palette.SendTxHook = func(_ *types.Transaction) error {
+		sent++
+		return nil
+	}
+	env.emitPolyDeposit(10, 1)
+	env.emitPolyDeposit(11, 2)
+	require.True(t, mgr.handleDepositEvents(10))
+	require.True(t, mgr.handleDepositEvents(11))
+
+	start := time.Now()
+	mgr.Stop()
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+
+	// the second transaction is dropped without sending after drain timeout.
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, 0, len(minedTxs(env.palette)))
+}
+
+func countPaletteTxs(t *testing.T, boltDB *db.BoltDB) int {
+	cnt := 0
+	require.NoError(t, boltDB.ForEachPaletteTx(func(_, _ []byte) { cnt++ }))
+	return cnt
+}
+
+func TestFakePolyManagerReplay(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.DrainTimeout = 1
+	mgr := env.polyManager(t)
+	sender := mgr.senders[0]
+
+	// transactions are not sent before drain timeout.
+	sender.nonceManager.UseNonce(sender.acc.Address)
+	env.emitPolyDeposit(10, 1)
+	env.emitPolyDeposit(11, 2)
+	require.True(t, mgr.handleDepositEvents(10))
+	require.True(t, mgr.handleDepositEvents(11))
+	mgr.Stop()
+	assert.Equal(t, 2, countPaletteTxs(t, env.db))
+
+	// poly tx relayed by others is not replayed.
+	env.palette.MarkRelayed(fakeToChainID, pltcm.BytesToHash([]byte{2}))
+	sent := 0
+	env.palette.SendTxHook = func(_ *types.Transaction) error {
+		sent++
+		return nil
+	}
+	env.cfg.DrainTimeout = 0
+	mgr = env.polyManager(t)
+	mgr.Stop()
+
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
+
+func TestFakePolyManagerReplayCurrentHeight(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.DrainTimeout = 1
+	mgr := env.polyManager(t)
+
+	// the transaction is not sent before shutdown, and the poly height is checkpointed.
+	env.palette.SendTxHook = func(_ *types.Transaction) error {
+		return fmt.Errorf("connection refused")
+	}
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	require.NoError(t, env.db.UpdatePolyHeight(10))
+	mgr.Stop()
+	require.Equal(t, 1, countPaletteTxs(t, env.db))
+
+	// the replayed transaction is held until the current height handled again.
+	var sent int32
+	release := make(chan struct{})
+	env.palette.SendTxHook = func(_ *types.Transaction) error {
+		atomic.AddInt32(&sent, 1)
+		<-release
+		return nil
+	}
+	env.cfg.DrainTimeout = 0
+	mgr = env.polyManager(t)
+	require.Equal(t, uint32(10), mgr.currentHeight)
+	require.True(t, mgr.handleDepositEvents(mgr.currentHeight))
+	close(release)
+	waitMined(t, env, 1)
+	mgr.Stop()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&sent))
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
+
+// exhaustingSelector pick the first candidate and mark it unhealthy, so no
+// sender is left for the next selection.
+type exhaustingSelector struct{}
+
+func (exhaustingSelector) name() string      { return "exhausting" }
+func (exhaustingSelector) needBalance() bool { return false }
+func (exhaustingSelector) pick(candidates []*senderCandidate) *PaletteSender {
+	s := candidates[0].sender
+	atomic.StoreInt64(&s.unhealthyUntil, time.Now().Add(time.Hour).Unix())
+	return s
+}
+
+func TestFakePolyManagerNoSenderForHeight(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	mgr := env.polyManager(t)
+
+	var sent int32
+	env.palette.SendTxHook = func(_ *types.Transaction) error {
+		atomic.AddInt32(&sent, 1)
+		return nil
+	}
+	env.emitPolyDeposit(10, 1)
+	env.emitPolyDeposit(10, 2)
+
+	// nothing is persisted when there is no sender for the second deposit.
+	mgr.selector = exhaustingSelector{}
+	require.False(t, mgr.handleDepositEvents(10))
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+
+	// the height handled again relays each deposit once.
+	atomic.StoreInt64(&mgr.senders[0].unhealthyUntil, 0)
+	mgr.selector = randomSelector{}
+	require.True(t, mgr.handleDepositEvents(10))
+	waitMined(t, env, 2)
+	mgr.Stop()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&sent))
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
+
+type fakeTxView struct {
+	to   pltcm.Address
+	data []byte
+}
+
+func minedTxs(chain *fake.PaletteChain) []*fakeTxView {
+	list := make([]*fakeTxView, 0)
+	for _, tx := range chain.Transactions() {
+		list = append(list, &fakeTxView{to: *tx.To(), data: tx.Data()})
+	}
+	return list
+}
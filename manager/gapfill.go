@@ -0,0 +1,98 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/palettechain/palette-relayer/log"
+	"github.com/palettechain/palette-relayer/utils/nonce"
+)
+
+const (
+	// defaultNonceGapTimeout is used if `PaletteConfig.NonceGapTimeout` is not configured.
+	defaultNonceGapTimeout = time.Minute
+
+	// fillerGasLimit is the gas limit of zero-value self-transfer which fills nonce gap.
+	fillerGasLimit uint64 = 21000
+)
+
+// fillNonceGaps check nonce gaps of senders every `PaletteConfig.NonceGapTimeout` until `ctx` is done.
+func (m *PolyManager) fillNonceGaps(ctx context.Context) {
+	ticker := time.NewTicker(m.nonceGapTimeout())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, s := range m.senders {
+				s.fillNonceGaps(m.nonceGapTimeout())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fillNonceGaps send zero-value self-transfers with nonces which are returned longer than `timeout`
+// but never reused, so that transactions with higher nonces are unblocked.
+func (s *PaletteSender) fillNonceGaps(timeout time.Duration) {
+	s.nonceManager.Resync(s.acc.Address)
+	for _, v := range s.nonceManager.TakeGaps(s.acc.Address, timeout) {
+		hash, err := s.sendFiller(v)
+		if err != nil {
+			log.Errorf("PolyManager fillNonceGaps - fill nonce %d of sender %s error: %v", v, s.acc.Address.Hex(), err)
+			s.nonceManager.ReturnNonce(s.acc.Address, v)
+			if nonce.IsNonceError(err) {
+				s.nonceManager.Resync(s.acc.Address)
+			}
+			continue
+		}
+		log.Warnf("PolyManager fillNonceGaps - nonce %d of sender %s is returned longer than %s, "+
+			"filled by self-transfer %s", v, s.acc.Address.Hex(), timeout, hash)
+	}
+}
+
+// sendFiller broadcast the zero-value self-transfer with nonce `curNonce`.
+func (s *PaletteSender) sendFiller(curNonce uint64) (string, error) {
+	gasPrice, err := s.gasPrice()
+	if err != nil {
+		return "", fmt.Errorf("get gas price error: %s", err)
+	}
+
+	tx := types.NewTransaction(curNonce, s.acc.Address, big.NewInt(0), fillerGasLimit, gasPrice, nil)
+	signedTx, err := s.keyStore.SignTransaction(tx, s.acc)
+	if err != nil {
+		return "", fmt.Errorf("sign self-transfer error: %s", err)
+	}
+	if err := s.paletteClient.SendTransaction(s.ctx, signedTx, bind.PrivateTxArgs{}); err != nil {
+		return "", fmt.Errorf("send self-transfer error: %s", err)
+	}
+	return signedTx.Hash().String(), nil
+}
+
+func (m *PolyManager) nonceGapTimeout() time.Duration {
+	if m.config.PaletteConfig.NonceGapTimeout <= 0 {
+		return defaultNonceGapTimeout
+	}
+	return time.Duration(m.config.PaletteConfig.NonceGapTimeout) * time.Second
+}
@@ -0,0 +1,78 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/palettechain/palette-relayer/config"
+	"github.com/palettechain/palette-relayer/log"
+)
+
+const (
+	// GasPriceFixed use `PaletteConfig.GasPrice` for every palette transaction, it's the default strategy.
+	GasPriceFixed = "fixed"
+
+	// GasPriceSuggested use the gas price suggested by palette node multiplied by `PaletteConfig.GasPriceMultiplier`.
+	GasPriceSuggested = "suggested"
+)
+
+// validateGasPrice check the gas price strategy of palette transactions.
+func validateGasPrice(cfg *config.PaletteConfig) error {
+	switch cfg.GasPriceStrategy {
+	case "", GasPriceFixed, GasPriceSuggested:
+	default:
+		return fmt.Errorf("unknown gas price strategy %s", cfg.GasPriceStrategy)
+	}
+	if cfg.GasPriceMultiplier < 0 {
+		return fmt.Errorf("negative gas price multiplier %v", cfg.GasPriceMultiplier)
+	}
+	if cfg.MaxGasPrice > 0 && cfg.MinGasPrice > cfg.MaxGasPrice {
+		return fmt.Errorf("min gas price %d is greater than max gas price %d", cfg.MinGasPrice, cfg.MaxGasPrice)
+	}
+	return nil
+}
+
+// gasPrice choose the gas price of next palette transaction by the strategy, and the price is capped
+// by `PaletteConfig.MinGasPrice` and `PaletteConfig.MaxGasPrice` if they are configured.
+func (s *PaletteSender) gasPrice() (*big.Int, error) {
+	cfg := s.config.PaletteConfig
+
+	price := new(big.Int).SetUint64(cfg.GasPrice)
+	if cfg.GasPriceStrategy == GasPriceSuggested {
+		suggested, err := s.paletteClient.SuggestGasPrice(s.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("suggest gas price err: %s", err)
+		}
+		price = suggested
+		if cfg.GasPriceMultiplier > 0 {
+			// round to the nearest integer, since the multiplier is not exact in binary.
+			f := new(big.Float).Mul(new(big.Float).SetInt(suggested), big.NewFloat(cfg.GasPriceMultiplier))
+			price, _ = f.Add(f, big.NewFloat(0.5)).Int(nil)
+		}
+	}
+
+	if min := new(big.Int).SetUint64(cfg.MinGasPrice); price.Cmp(min) < 0 {
+		price = min
+	}
+	if max := new(big.Int).SetUint64(cfg.MaxGasPrice); cfg.MaxGasPrice > 0 && price.Cmp(max) > 0 {
+		log.Warnf("PolyManager gasPrice - gas price %s exceeds max gas price, capped to %s", price, max)
+		price = max
+	}
+	return price, nil
+}
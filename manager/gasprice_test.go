@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/palettechain/palette-relayer/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGasPrice(t *testing.T) {
+	chain := newFakeEnv(t).palette
+	chain.GasPrice = big.NewInt(100)
+
+	cases := []struct {
+		name   string
+		cfg    *config.PaletteConfig
+		expect int64
+	}{
+		{"default", &config.PaletteConfig{}, 0},
+		{"fixed", &config.PaletteConfig{GasPriceStrategy: GasPriceFixed, GasPrice: 20}, 20},
+		{"fixed min", &config.PaletteConfig{GasPrice: 20, MinGasPrice: 30}, 30},
+		{"suggested", &config.PaletteConfig{GasPriceStrategy: GasPriceSuggested}, 100},
+		{"suggested multiplier", &config.PaletteConfig{GasPriceStrategy: GasPriceSuggested, GasPriceMultiplier: 1.5}, 150},
+		{"suggested max", &config.PaletteConfig{GasPriceStrategy: GasPriceSuggested, GasPriceMultiplier: 2, MaxGasPrice: 120}, 120},
+		{"suggested min", &config.PaletteConfig{GasPriceStrategy: GasPriceSuggested, GasPriceMultiplier: 0.5, MinGasPrice: 80}, 80},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.NoError(t, validateGasPrice(c.cfg))
+			s := &PaletteSender{
+				config:        &config.ServiceConfig{PaletteConfig: c.cfg},
+				paletteClient: chain,
+				ctx:           context.Background(),
+			}
+			price, err := s.gasPrice()
+			require.NoError(t, err)
+			assert.Equal(t, big.NewInt(c.expect), price)
+		})
+	}
+
+	assert.Error(t, validateGasPrice(&config.PaletteConfig{GasPriceStrategy: "oracle"}))
+	assert.Error(t, validateGasPrice(&config.PaletteConfig{MinGasPrice: 20, MaxGasPrice: 10}))
+	assert.Error(t, validateGasPrice(&config.PaletteConfig{GasPriceMultiplier: -1}))
+}
+
+func TestFakePolyToPaletteGasPrice(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.GasPriceStrategy = GasPriceSuggested
+	env.cfg.PaletteConfig.GasPriceMultiplier = 1.2
+	env.palette.GasPrice = big.NewInt(1000)
+	mgr := env.polyManager(t)
+
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	mgr.Stop()
+
+	txs := env.palette.Transactions()
+	require.Equal(t, 1, len(txs))
+	assert.Equal(t, big.NewInt(1200), txs[0].GasPrice())
+}
+
+func TestBumpGasPrice(t *testing.T) {
+	s := &PaletteSender{config: &config.ServiceConfig{PaletteConfig: &config.PaletteConfig{MaxGasPrice: 150}}}
+
+	cases := []struct {
+		price  int64
+		expect int64
+		ok     bool
+	}{
+		{0, 1, true},
+		{5, 6, true},
+		{100, 110, true},
+		{140, 150, true},
+		{150, 0, false},
+	}
+	for _, c := range cases {
+		bumped, ok := s.bumpGasPrice(big.NewInt(c.price))
+		require.Equal(t, c.ok, ok, "price %d", c.price)
+		if ok {
+			assert.Equal(t, big.NewInt(c.expect), bumped, "price %d", c.price)
+		}
+	}
+}
+
+func TestFakePolyToPaletteReplaceStuckTx(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 100
+	env.cfg.PaletteConfig.GasPrice = 50
+	env.cfg.PaletteConfig.PendingTimeout = 1
+	env.cfg.PaletteConfig.GasPriceBump = 50
+	env.palette.MinGasPrice = big.NewInt(100)
+	mgr := env.polyManager(t)
+
+	sent := make([]*types.Transaction, 0)
+	env.palette.SendTxHook = func(tx *types.Transaction) error {
+		sent = append(sent, tx)
+		return nil
+	}
+
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	mgr.Stop()
+
+	// the transaction is replaced twice with the same nonce until it's mined.
+	require.Equal(t, 3, len(sent))
+	for i, expect := range []int64{50, 75, 112} {
+		assert.Equal(t, big.NewInt(expect), sent[i].GasPrice())
+		assert.Equal(t, sent[0].Nonce(), sent[i].Nonce())
+	}
+	txs := env.palette.Transactions()
+	require.Equal(t, 1, len(txs))
+	assert.Equal(t, sent[2].Hash(), txs[0].Hash())
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
@@ -36,7 +36,13 @@ var (
 	testPolyMgr        *PolyManager
 )
 
+// TestMain connect palette and poly nodes configured in `config_test.json`. tests which depend
+// on live nodes are skipped if the config file not exist, scenarios built on package `fake` always run.
 func TestMain(m *testing.M) {
+	if _, err := os.Stat(testConfigPath); err != nil {
+		fmt.Printf("live node tests skipped, config %s not found\n", testConfigPath)
+		os.Exit(m.Run())
+	}
 	srvConfig := config.NewServiceConfig(testConfigPath)
 
 	// create poly sdk
@@ -91,6 +97,12 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+func requireLive(t *testing.T) {
+	if testPLTMgr == nil || testPolyMgr == nil {
+		t.Skip("palette and poly nodes not configured")
+	}
+}
+
 func fullPath(p string) string {
 	if path.IsAbs(p) {
 		return p
@@ -21,10 +21,9 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	pltcm "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	plttyp "github.com/ethereum/go-ethereum/core/types"
@@ -36,6 +35,7 @@ import (
 	"github.com/palettechain/palette-relayer/utils/rest"
 	"github.com/polynetwork/eth-contracts/go_abi/eccm_abi"
 	polysdk "github.com/polynetwork/poly-go-sdk"
+	polycm "github.com/polynetwork/poly/common"
 	ccm "github.com/polynetwork/poly/native/service/cross_chain_manager/common"
 	synccm "github.com/polynetwork/poly/native/service/header_sync/common"
 	autils "github.com/polynetwork/poly/native/service/utils"
@@ -64,20 +64,35 @@ type PaletteManager struct {
 	config *config.ServiceConfig
 	db     *db.BoltDB
 
-	restClient       *rest.RestClient
-	paletteClient    *pltcli.Client
-	paletteLockProxy *eccm_abi.EthCrossChainManager
-	polySdk          *polysdk.PolySdk
-	polySigner       *polysdk.Account
+	paletteClient PaletteSource
+	polySdk       PolyClient
+	polySigner    *polysdk.Account
 
-	currentSyncHeaderHeight,
-	currentDepositHeight,
+	// syncProgress is the next block to be synced by `MonitorChain`, and depositProgress is
+	// the next block to be handled by `MonitorDeposit`, which never exceeds syncProgress.
+	syncProgress,
+	depositProgress *progress
 	forceHeight uint64
 
+	// cross transfers in `retry` bucket waiting for safe distance or backoff.
+	deposits *depositQueue
+	// the last poly transaction checked in `check` bucket, checking resumes after it in next round.
+	checkCursor []byte
+	// the number of poly transactions which are not confirmed in time, it's updated atomically.
+	checkTimeouts uint64
+	// the last time backlog reported by `logBacklog`.
+	backlogLogTime time.Time
+
 	lastEpoch,
 	curHeader *pltEpoch
 
-	exitChan chan int
+	// epoch headers which are found on palette chain but not synced to poly chain yet.
+	pendingHeaders []*pltEpoch
+	// epoch headers which are submitted to poly chain and waiting for confirmation.
+	inflight *HeaderCommit
+
+	// routines started by `Start`.
+	wg sync.WaitGroup
 }
 
 func NewPaletteManager(
@@ -94,10 +109,6 @@ func NewPaletteManager(
 		return nil, err
 	}
 
-	if len(cfg.TargetContracts) == 0 {
-		return nil, fmt.Errorf("NewETHManager - no target contracts")
-	}
-
 	lockAddress := pltcm.HexToAddress(cfg.PaletteConfig.ECCMContractAddress)
 	lockContract, err := eccm_abi.NewEthCrossChainManager(lockAddress, paletteClient)
 	if err != nil {
@@ -105,20 +116,38 @@ func NewPaletteManager(
 		return nil, err
 	}
 
-	restCli := rest.NewRestClient()
-	palette.Initialize(cfg.PaletteConfig.RestURL, restCli)
+	palette.Initialize(cfg.PaletteConfig.RestURL, rest.NewRestClient())
+	source := &paletteSource{Client: paletteClient, eccm: lockContract}
+
+	return newPaletteManager(cfg, startHeight, startForceHeight, source, &polyClient{polySdk}, signer, boltDB)
+}
+
+// newPaletteManager assemble palette manager with chain access interfaces, which allows
+// tests to replace palette and poly nodes with in-memory fakes.
+func newPaletteManager(
+	cfg *config.ServiceConfig,
+	startHeight,
+	startForceHeight uint64,
+	paletteClient PaletteSource,
+	polySdk PolyClient,
+	signer *polysdk.Account,
+	boltDB *db.BoltDB,
+) (*PaletteManager, error) {
+
+	if len(cfg.TargetContracts) == 0 {
+		return nil, fmt.Errorf("NewETHManager - no target contracts")
+	}
 
 	mgr := &PaletteManager{
-		config:                  cfg,
-		exitChan:                make(chan int),
-		currentSyncHeaderHeight: startHeight,
-		forceHeight:             startForceHeight,
-		restClient:              restCli,
-		paletteClient:           paletteClient,
-		paletteLockProxy:        lockContract,
-		polySdk:                 polySdk,
-		polySigner:              signer,
-		db:                      boltDB,
+		config:          cfg,
+		syncProgress:    newProgress(startHeight),
+		depositProgress: newProgress(0),
+		deposits:        newDepositQueue(),
+		forceHeight:     startForceHeight,
+		paletteClient:   paletteClient,
+		polySdk:         polySdk,
+		polySigner:      signer,
+		db:              boltDB,
 	}
 
 	if err := mgr.init(); err != nil {
@@ -141,6 +170,11 @@ func (m *PaletteManager) init() error {
 	if !m.fetchLastEpoch(lastEpoch) {
 		return fmt.Errorf("init - find the genesis header failded")
 	}
+	m.restoreHeaderCommit()
+	m.restoreValSet()
+	if err := m.loadDeposits(); err != nil {
+		return fmt.Errorf("init - load deposits err: %s", err)
+	}
 
 	curHeight := m.db.GetPaletteHeight()
 	if curHeight == 0 {
@@ -150,66 +184,168 @@ func (m *PaletteManager) init() error {
 		curHeight = m.forceHeight
 	}
 
-	m.currentSyncHeaderHeight = curHeight
-	m.currentDepositHeight = curHeight
+	m.syncProgress.set(curHeight)
+	m.depositProgress.set(curHeight)
 	log.Infof("PaletteManager init - start height: %d", curHeight)
 
 	return nil
 }
 
+// Start run `MonitorChain`, `MonitorDeposit` and `CheckDeposit` in background until `ctx` is done.
+func (m *PaletteManager) Start(ctx context.Context) {
+	for _, routine := range []func(context.Context){m.MonitorChain, m.MonitorDeposit, m.CheckDeposit} {
+		m.wg.Add(1)
+		go func(routine func(context.Context)) {
+			defer m.wg.Done()
+			routine(ctx)
+		}(routine)
+	}
+}
+
+// Stop wait for routines started by `Start` exit, it should be called after the context is done.
+func (m *PaletteManager) Stop() {
+	m.wg.Wait()
+	log.Infof("palette chain manager exit.")
+}
+
 // MonitorChain the `paletteManager` needs to traverse all of blocks and events on the palette chain,
 // and record the relationship of block height and block content which contains block header and event logs.
 // and these data should be synced to `headerSync` and `crossChainManager` contracts located on poly chain.
-func (m *PaletteManager) MonitorChain() {
+func (m *PaletteManager) MonitorChain(ctx context.Context) {
 	ticker := time.NewTicker(config.PLT_MONITOR_INTERVAL)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
-			height, err := palette.GetNodeHeight()
+			height, err := m.paletteClient.GetNodeHeight()
 			if err != nil {
 				log.Infof("PaletteManager MonitorChain - cannot get node height, err: %s", err)
 				continue
 			}
+			m.handleBlocks(ctx, height)
 
-			for m.currentSyncHeaderHeight < height {
-				if m.handleNewBlock(m.currentSyncHeaderHeight) {
-					_ = m.db.UpdatePaletteHeight(m.currentSyncHeaderHeight)
-					m.currentSyncHeaderHeight++
-					log.Infof("PaletteManager MonitorChain - current height %d, palette height is %d",
-						m.currentSyncHeaderHeight, height)
-				} else {
-					time.Sleep(1 * time.Second)
-				}
-			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
 
-		case <-m.exitChan:
+// handleBlocks process blocks before palette node `height` in order, the hash of every processed block
+// is recorded as checkpoint, and the manager rolls back to the common ancestor if palette chain reorganized.
+// epoch headers are committed to poly chain in batches without waiting for confirmation, and the rest of
+// them are committed after all of blocks processed. it returns as soon as `ctx` is done.
+func (m *PaletteManager) handleBlocks(ctx context.Context, height uint64) {
+	for m.syncProgress.get() < height {
+		if m.handleBlockRange(ctx, height) {
+			continue
+		}
+		select {
+		case <-time.After(1 * time.Second):
+		case <-ctx.Done():
 			return
 		}
 	}
+
+	if len(m.pendingHeaders) > 0 || m.inflight != nil {
+		_ = m.commitHeaders()
+	}
 }
 
-func (m *PaletteManager) MonitorDeposit() {
-	ticker := time.NewTicker(config.PLT_MONITOR_INTERVAL)
+// handleBlockRange process prefetched blocks from current sync height to `height` in order. it returns false
+// if any block failed, and returns true as soon as the manager rolled back, so that the caller is able to
+// restart prefetching from the new sync height. it returns false if `ctx` is done.
+func (m *PaletteManager) handleBlockRange(ctx context.Context, height uint64) bool {
+	quit := make(chan struct{})
+	defer close(quit)
+
+	for result := range m.prefetchBlocks(m.syncProgress.get(), height, quit) {
+		if ctx.Err() != nil {
+			return false
+		}
+		blk := <-result
+		if blk.height != m.syncProgress.get() {
+			return true
+		}
+		if !m.handleBlock(blk) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleBlock check the prefetched block against checkpoints before handling it, and move
+// the sync height forward if succeed.
+func (m *PaletteManager) handleBlock(blk *pltBlock) bool {
+	if blk.err != nil {
+		log.Errorf("PaletteManager handleBlock - fetch block %d err: %s", blk.height, blk.err)
+		return false
+	}
+	if len(m.pendingHeaders) >= m.headersPerBatch() && !m.commitHeaders() {
+		return false
+	}
+
+	ancestor, reorg, err := m.detectReorg(blk.header)
+	if err != nil {
+		log.Errorf("PaletteManager handleBlock - detect reorg on height %d err: %s", blk.height, err)
+		return false
+	}
+	if reorg {
+		m.rollback(ancestor)
+		return true
+	}
+
+	if !m.handleNewBlock(blk) {
+		return false
+	}
+	_ = m.db.UpdatePaletteHeight(blk.height)
+	if err := m.db.PutPaletteBlockHash(blk.height, blk.header.Hash().Bytes()); err != nil {
+		log.Errorf("PaletteManager handleBlock - record hash of block %d err: %s", blk.height, err)
+	}
+	m.syncProgress.advance(blk.height)
+	log.Infof("PaletteManager handleBlock - current height %d", blk.height+1)
+	return true
+}
+
+// MonitorDeposit is woken up whenever `MonitorChain` makes progress, and handles deposits
+// until it catches up with synced blocks.
+func (m *PaletteManager) MonitorDeposit(ctx context.Context) {
+	synced := m.syncProgress.subscribe()
+	defer m.syncProgress.unsubscribe(synced)
+
+	m.handleDeposits(ctx)
 	for {
 		select {
-		case <-ticker.C:
-			for m.currentDepositHeight < m.currentSyncHeaderHeight {
-				_ = m.handleDepositEvents(m.currentDepositHeight)
-				m.currentDepositHeight++
-			}
-		case <-m.exitChan:
+		case <-synced:
+			m.handleDeposits(ctx)
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (m *PaletteManager) CheckDeposit() {
+// handleDeposits handle deposits with every synced block as reference height in order. the deposit
+// progress is not moved forward if it's rolled back by `MonitorChain` meanwhile.
+func (m *PaletteManager) handleDeposits(ctx context.Context) {
+	for ctx.Err() == nil {
+		height := m.depositProgress.get()
+		if height >= m.syncProgress.get() {
+			return
+		}
+		_ = m.handleDepositEvents(height)
+		m.depositProgress.advance(height)
+	}
+}
+
+func (m *PaletteManager) CheckDeposit(ctx context.Context) {
 	ticker := time.NewTicker(config.PLT_MONITOR_INTERVAL)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
 			_ = m.checkLockEvents()
-		case <-m.exitChan:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -222,23 +358,33 @@ func (m *PaletteManager) findLastEpochHeight() uint64 {
 	return bytesToUint64(result)
 }
 
-// handleNewBlock retry if handle block header failed. if handle events failed, just ignore.
-func (m *PaletteManager) handleNewBlock(height uint64) bool {
+// handleNewBlock retry if handle block header failed. events of the block are already filtered
+// by `fetchBlock`, and invalid events are just ignored.
+// epoch header is refused if its seals can not be verified with the last known validators, otherwise
+// it's cached in `pendingHeaders` and committed to poly chain by `handleBlocks` later.
+func (m *PaletteManager) handleNewBlock(blk *pltBlock) bool {
+	height := blk.height
 	if m.checkEpochHeight(height) {
-		if !m.fetchBlockHeader(height) {
-			log.Errorf("PaletteManager handleNewBlock - fetchBlockHeader on height :%d failed", height)
+		if !m.updateBlockHeader(height, blk.header) {
+			log.Errorf("PaletteManager handleNewBlock - updateBlockHeader on height :%d failed", height)
 			return false
 		}
 
-		if m.isEpoch() && !m.commitHeader() {
-			log.Errorf("PaletteManager handleNewBlock - commitHeader on height :%d failed", height)
-			return false
+		if m.isEpoch() {
+			// epoch header is committed by validators of the last epoch.
+			if err := verifyPaletteHeader(blk.header, m.latestEpoch().valset); err != nil {
+				log.Errorf("PaletteManager handleNewBlock - verify epoch header on height %d err: %s", height, err)
+				m.curHeader = nil
+				return false
+			}
+			m.pendingHeaders = append(m.pendingHeaders, m.curHeader)
+			m.recordValSet(blk.header, m.curHeader)
+			log.Infof("PaletteManager handleNewBlock - found epoch header on height %d, valset size %d",
+				height, len(m.curHeader.valset))
 		}
 	}
 
-	if !m.fetchLockEvents(height) {
-		log.Errorf("PaletteManager handleNewBlock - fetchLockEvents on height :%d failed", height)
-	}
+	m.handleLockEvents(height, blk.events)
 	return true
 }
 
@@ -256,10 +402,19 @@ func (m *PaletteManager) fetchBlockHeader(height uint64) bool {
 		return false
 	}
 
+	return m.updateBlockHeader(height, hdr)
+}
+
+// updateBlockHeader cache the header and its validators as current header.
+func (m *PaletteManager) updateBlockHeader(height uint64, hdr *plttyp.Header) bool {
+	if m.curHeader != nil && m.curHeader.height == height {
+		return true
+	}
+
 	// compare header
 	raw, err := hdr.MarshalJSON()
 	if err != nil {
-		log.Errorf("PaletteManager fetchBlockHeader - marshal current block header err: %s", err)
+		log.Errorf("PaletteManager updateBlockHeader - marshal current block header err: %s", err)
 		return false
 	}
 	if m.curHeader != nil && bytes.Equal(raw, m.curHeader.raw) {
@@ -269,7 +424,7 @@ func (m *PaletteManager) fetchBlockHeader(height uint64) bool {
 	// get validators
 	extra, err := plttyp.ExtractIstanbulExtra(hdr)
 	if err != nil {
-		log.Errorf("PaletteManager fetchBlockHeader - extract istanbul extra err: %s", err)
+		log.Errorf("PaletteManager updateBlockHeader - extract istanbul extra err: %s", err)
 		return false
 	}
 
@@ -309,45 +464,185 @@ func (m *PaletteManager) fetchLastEpoch(height uint64) bool {
 	return true
 }
 
-func (m *PaletteManager) commitHeader() bool {
-	tx, err := m.polySdk.Native.Hs.SyncBlockHeader(
+// commitHeaders check the in-flight header commitment first, and submit the next batch of pending headers
+// if nothing is in-flight. it never blocks, and returns false if pending headers can not be submitted now.
+func (m *PaletteManager) commitHeaders() bool {
+	if m.inflight != nil && !m.checkHeaderCommit() {
+		return false
+	}
+	if len(m.pendingHeaders) == 0 {
+		return true
+	}
+	return m.submitHeaders()
+}
+
+// submitHeaders sync at most `HeadersPerBatch` pending epoch headers to poly chain in one transaction,
+// and persist them as in-flight commitment.
+func (m *PaletteManager) submitHeaders() bool {
+	batch := m.pendingHeaders
+	if n := m.headersPerBatch(); len(batch) > n {
+		batch = batch[:n]
+	}
+	raws := make([][]byte, len(batch))
+	for i, hdr := range batch {
+		raws[i] = hdr.raw
+	}
+
+	tx, err := m.polySdk.SyncBlockHeader(
 		m.sideChainID(),
 		m.polySigner.Address,
-		[][]byte{m.curHeader.raw},
+		raws,
 		m.polySigner,
 	)
 	if err != nil {
-		log.Errorf("PaletteManager commitHeader - sync block header err: %s", err)
+		log.Errorf("PaletteManager submitHeaders - sync block header err: %s", err)
 		return false
 	}
 
-	// waiting for transaction confirmed on poly chain, and the landmark event is that
-	// current block height on poly chain is bigger than tx's height.
-	var h uint32
-	ticker := time.NewTicker(1 * time.Second)
-	for range ticker.C {
-		if h == 0 {
-			h, _ = m.polySdk.GetBlockHeightByTxHash(tx.ToHexString())
-		} else {
-			curr, _ := m.polySdk.GetCurrentBlockHeight()
-			if curr > h {
-				m.lastEpoch.height = m.curHeader.height
-				m.lastEpoch.raw = m.curHeader.raw
-				m.lastEpoch.valset = m.curHeader.valset
-				break
+	commit := &HeaderCommit{
+		txHash:     tx.ToHexString(),
+		submitTime: time.Now().Unix(),
+		headers:    batch,
+	}
+	sink := polycm.NewZeroCopySink(nil)
+	commit.Serialization(sink)
+	if err := m.db.PutHeaderCommit(commit.txHash, sink.Bytes()); err != nil {
+		log.Errorf("PaletteManager submitHeaders - m.db.PutHeaderCommit error: %s", err)
+	}
+	m.inflight = commit
+	m.pendingHeaders = m.pendingHeaders[len(batch):]
+
+	log.Infof("PaletteManager submitHeaders - send (poly transaction %s, palette header height %d-%d, batch size %d) "+
+		"to poly chain", commit.txHash, batch[0].height, batch[len(batch)-1].height, len(batch))
+
+	return true
+}
+
+// checkHeaderCommit return true if the in-flight commitment is settled. the last epoch is updated if the
+// poly transaction succeed, otherwise headers which are not synced yet are pushed back to pending list and
+// resubmitted later.
+func (m *PaletteManager) checkHeaderCommit() bool {
+	commit := m.inflight
+	confirmed, err := m.headerCommitConfirmed(commit.txHash)
+	switch {
+	case err != nil:
+		log.Errorf("PaletteManager checkHeaderCommit - poly tx %s failed, err: %s", commit.txHash, err)
+
+	case confirmed:
+		last := commit.headers[len(commit.headers)-1]
+		m.lastEpoch.height = last.height
+		m.lastEpoch.raw = last.raw
+		m.lastEpoch.valset = last.valset
+		m.finishHeaderCommit()
+		log.Infof("PaletteManager checkHeaderCommit - poly tx %s confirmed, palette header height %d-%d",
+			commit.txHash, commit.headers[0].height, last.height)
+		return true
+
+	case time.Since(time.Unix(commit.submitTime, 0)) > m.headerCommitTimeout():
+		log.Errorf("PaletteManager checkHeaderCommit - poly tx %s not confirmed in %s", commit.txHash,
+			m.headerCommitTimeout())
+
+	default:
+		return false
+	}
+
+	// the transaction may land after timeout, and poly chain refuses headers which are already synced,
+	// so they are dropped rather than resubmitted.
+	synced := m.findLastEpochHeight()
+	pending := make([]*pltEpoch, 0, len(commit.headers)+len(m.pendingHeaders))
+	for _, hdr := range append(commit.headers, m.pendingHeaders...) {
+		if hdr.height > synced {
+			pending = append(pending, hdr)
+			continue
+		}
+		m.lastEpoch.height = hdr.height
+		m.lastEpoch.raw = hdr.raw
+		m.lastEpoch.valset = hdr.valset
+		log.Infof("PaletteManager checkHeaderCommit - palette header %d is already synced to poly chain, "+
+			"synced height %d", hdr.height, synced)
+	}
+	m.pendingHeaders = pending
+	m.finishHeaderCommit()
+	return true
+}
+
+// headerCommitConfirmed return true if the poly transaction which synced headers is confirmed, and the
+// landmark event is that current block height on poly chain is bigger than tx's height and the transaction
+// executed successfully.
+func (m *PaletteManager) headerCommitConfirmed(txHash string) (bool, error) {
+	h, err := m.polySdk.GetBlockHeightByTxHash(txHash)
+	if err != nil || h == 0 {
+		return false, nil
+	}
+	curr, err := m.polySdk.GetCurrentBlockHeight()
+	if err != nil || curr <= h {
+		return false, nil
+	}
+	event, err := m.polySdk.GetSmartContractEvent(txHash)
+	if err != nil || event == nil {
+		return false, nil
+	}
+	if event.State != 1 {
+		return false, fmt.Errorf("state of poly tx is %d", event.State)
+	}
+	return true, nil
+}
+
+func (m *PaletteManager) finishHeaderCommit() {
+	if err := m.db.DeleteHeaderCommit(m.inflight.txHash); err != nil {
+		log.Errorf("PaletteManager finishHeaderCommit - m.db.DeleteHeaderCommit error: %s", err)
+	}
+	m.inflight = nil
+}
+
+// restoreHeaderCommit load the in-flight header commitment persisted before restart, so that
+// it will be checked rather than resubmitted or forgotten.
+func (m *PaletteManager) restoreHeaderCommit() {
+	commitMap, err := m.db.GetAllHeaderCommit()
+	if err != nil {
+		log.Errorf("PaletteManager restoreHeaderCommit - m.db.GetAllHeaderCommit error: %s", err)
+		return
+	}
+
+	for txHash, v := range commitMap {
+		commit := new(HeaderCommit)
+		if err := commit.Deserialization(polycm.NewZeroCopySource(v)); err != nil || len(commit.headers) == 0 {
+			log.Errorf("PaletteManager restoreHeaderCommit - invalid header commitment of poly tx %s", txHash)
+			_ = m.db.DeleteHeaderCommit(txHash)
+			continue
+		}
+
+		// only one commitment could be in-flight, keep the latest one.
+		if m.inflight != nil {
+			stale := m.inflight
+			if stale.headers[len(stale.headers)-1].height > commit.headers[len(commit.headers)-1].height {
+				stale, commit = commit, stale
 			}
+			_ = m.db.DeleteHeaderCommit(stale.txHash)
 		}
+		m.inflight = commit
 	}
 
-	log.Infof("PaletteManager commitHeader - send (palette transaction %s, palette header height %d, valset size %d) "+
-		"to poly chain and confirmed on poly height %d", tx.ToHexString(), m.curHeader.height, len(m.curHeader.valset), h)
+	if m.inflight != nil {
+		log.Infof("PaletteManager restoreHeaderCommit - restore poly tx %s which commits %d headers",
+			m.inflight.txHash, len(m.inflight.headers))
+	}
+}
 
-	return true
+// latestEpoch return the last pending or in-flight epoch header, or the last epoch synced to poly chain.
+func (m *PaletteManager) latestEpoch() *pltEpoch {
+	if n := len(m.pendingHeaders); n > 0 {
+		return m.pendingHeaders[n-1]
+	}
+	if m.inflight != nil {
+		return m.inflight.headers[len(m.inflight.headers)-1]
+	}
+	return m.lastEpoch
 }
 
 func (m *PaletteManager) isEpoch() bool {
 	s1 := m.curHeader.valset
-	s2 := m.lastEpoch.valset
+	s2 := m.latestEpoch().valset
 
 	if len(s1) != len(s2) {
 		return true
@@ -365,9 +660,9 @@ func (m *PaletteManager) isEpoch() bool {
 	return false
 }
 
-// checkEpochHeight return true if height is bigger than last pltEpoch height
+// checkEpochHeight return true if height is bigger than latest pltEpoch height
 func (m *PaletteManager) checkEpochHeight(height uint64) bool {
-	if height <= m.lastEpoch.height {
+	if height <= m.latestEpoch().height {
 		return false
 	}
 	return true
@@ -377,23 +672,19 @@ func (m *PaletteManager) checkEpochHeight(height uint64) bool {
 // filter events which has incorrect contract address or already exist in poly chain, and cache these
 // events data in `retry` bucket of blot database.
 func (m *PaletteManager) fetchLockEvents(height uint64) bool {
-	opt := &bind.FilterOpts{
-		Start:   height,
-		End:     &height,
-		Context: context.Background(),
-	}
-	iter, err := m.paletteLockProxy.FilterCrossChainEvent(opt, nil)
+	events, err := m.paletteClient.FilterCrossChainEvent(height, height)
 	if err != nil {
 		debug("PaletteManager fetchLockEvents - FilterCrossChainEvent error :%s", err.Error())
 		return false
 	}
-	if iter == nil {
-		debug("PaletteManager fetchLockEvents - no event iter found on FilterCrossChainEvent")
-		return false
-	}
 
-	for iter.Next() {
-		evt := iter.Event
+	m.handleLockEvents(height, events)
+	return true
+}
+
+// handleLockEvents filter cross chain events emitted in block at `height`, and cache them in `retry` bucket.
+func (m *PaletteManager) handleLockEvents(height uint64, events []*eccm_abi.EthCrossChainManagerCrossChainEvent) {
+	for _, evt := range events {
 		addr := evt.ProxyOrAssetContract
 		if !m.config.TargetContracts.CheckContract(addr, "outbound", evt.ToChainId) {
 			continue
@@ -406,67 +697,61 @@ func (m *PaletteManager) fetchLockEvents(height uint64) bool {
 
 		_, sink := serializeCrossTransfer(evt, height)
 		if err := m.db.PutRetry(sink.Bytes()); err != nil {
-			log.Errorf("PaletteManager fetchLockEvents - m.db.PutRetry error: %s", err)
+			log.Errorf("PaletteManager handleLockEvents - m.db.PutRetry error: %s", err)
 		} else {
-			log.Infof("PaletteManager fetchLockEvents -  height: %d", height)
+			m.deposits.push(sink.Bytes(), height)
+			log.Infof("PaletteManager handleLockEvents -  height: %d", height)
 		}
 	}
-	return true
 }
 
-// handleDepositEvents
+// handleDepositEvents commit proofs of cross transfers which reach the safe distance at `refHeight` or whose
+// backoff expired to poly chain, failed cross transfers are retried with exponential backoff until they exceed
+// max retry attempts.
 func (m *PaletteManager) handleDepositEvents(refHeight uint64) error {
-	retryList, err := m.db.GetAllRetry()
-	if err != nil {
-		return fmt.Errorf("handleDepositEvents - m.db.GetAllRetry error: %s", err)
-	}
+	// poly do not allow to verify header with validators in old epoch,
+	// we need to waiting for some blocks to fetch the latest block header and proof.
+	// and quorum only add/del single node in one epoch.
+	// safeHeight used for avoid chain fork, just need 1 block.
+	distance := m.safeBlockDistance()
+	items := m.deposits.popMature(refHeight, distance)
+	items = append(items, m.deposits.popDue(time.Now().Unix())...)
+
+	for _, item := range items {
+		v := item.key
+		if refHeight <= item.height+distance {
+			m.deposits.push(v, item.height)
+			continue
+		}
 
-	for _, v := range retryList {
 		crossTx, err := deserializeCrossTransfer(v)
 		if err != nil {
 			log.Errorf("PaletteManager handleDepositEvents - retry.Deserialization error: %s", err)
 			continue
 		}
 
-		// poly do not allow to verify header with validators in old epoch,
-		// we need to waiting for some blocks to fetch the latest block header and proof.
-		// and quorum only add/del single node in one epoch.
-		// safeHeight used for avoid chain fork, just need 1 block.
-		distance := m.safeBlockDistance()
-		if refHeight-distance <= crossTx.height {
-			log.Infof("PaletteManager handleDepositEvents - ignore tx %s, refHeight %d - distance %d <= crossTx height %d",
-				crossTx.txIndex, refHeight, distance, crossTx.height)
-			continue
-		}
 		safeHeight := refHeight - 1
 
 		// get proof from palette chain
 		proof, hdr, err := m.getProof(crossTx, safeHeight)
 		if err != nil {
 			log.Errorf("PaletteManager handleDepositEvents - get proof error :%s\n", err.Error())
+			m.handleDepositError(v, crossTx, err)
 			continue
 		}
 
 		// commit proof to poly chain success
 		txHash, err := m.commitProof(uint32(safeHeight), proof, crossTx.value, crossTx.txId, hdr)
 		if err != nil {
-			if strings.Contains(err.Error(), "chooseUtxos, current utxo is not enough") {
-				log.Infof("PaletteManager handleDepositEvents - invokeNativeContract error: %s", err)
-			} else if strings.Contains(err.Error(), "tx already done") {
-				log.Infof("PaletteManager handleDepositEvents - plt_tx %s already on poly", txIdHex(crossTx.txId))
-				if err := m.db.DeleteRetry(v); err != nil {
-					log.Errorf("PaletteManager handleDepositEvents - deleteRetry error: %s", err)
-				}
-			} else {
-				log.Errorf("PaletteManager handleDepositEvents - invoke NativeContract for block %d eth_tx %s: %s, err %s",
-					safeHeight, txIdHex(crossTx.txId), err)
-			}
+			log.Errorf("PaletteManager handleDepositEvents - invoke NativeContract for block %d plt_tx %s, err: %s",
+				safeHeight, txIdHex(crossTx.txId), err)
+			m.handleDepositError(v, crossTx, err)
 			continue
 		}
 
 		// process cache
-		if err := m.db.PutCheck(txHash, v); err != nil {
-			log.Errorf("PaletteManager handleDepositEvents - this.db.PutCheck error: %s", err)
+		if err := m.putCheck(txHash, v); err != nil {
+			log.Errorf("PaletteManager handleDepositEvents - m.putCheck error: %s", err)
 		}
 		if err := m.db.DeleteRetry(v); err != nil {
 			log.Errorf("PaletteManager handleDepositEvents - this.db.PutCheck error: %s", err)
@@ -476,6 +761,32 @@ func (m *PaletteManager) handleDepositEvents(refHeight uint64) error {
 	return nil
 }
 
+// handleDepositError decide what to do with the failed cross transfer by the category of error: delete it if
+// it is already done on poly chain, keep it without counting attempts and retry it after `PaletteConfig.RetryInterval`
+// if relayer or network is not ready, otherwise count the failed attempt and back off.
+func (m *PaletteManager) handleDepositError(v []byte, crossTx *CrossTransfer, err error) {
+	switch category := ClassifyError(err); category {
+	case ErrCategoryAlreadyDone:
+		log.Infof("PaletteManager handleDepositError - plt_tx %s already on poly", txIdHex(crossTx.txId))
+		if err := m.db.DeleteRetry(v); err != nil {
+			log.Errorf("PaletteManager handleDepositError - deleteRetry error: %s", err)
+		}
+
+	case ErrCategoryInsufficientFunds:
+		log.Errorf("PaletteManager handleDepositError - ALERT: poly signer %s has insufficient funds, err: %s",
+			m.polySigner.Address.ToBase58(), err)
+		m.deposits.delay(v, crossTx.height, time.Now().Add(m.retryInterval()).Unix())
+
+	case ErrCategoryHeaderNotSynced, ErrCategoryTransientNetwork:
+		log.Warnf("PaletteManager handleDepositError - plt_tx %s will be retried later, %s err: %s",
+			txIdHex(crossTx.txId), category, err)
+		m.deposits.delay(v, crossTx.height, time.Now().Add(m.retryInterval()).Unix())
+
+	default:
+		m.retryFailed(v, err)
+	}
+}
+
 func (m *PaletteManager) getProof(e *CrossTransfer, height uint64) (proof []byte, hdr []byte, err error) {
 	// decode events
 	keyBytes, err := getMappingKey(e.txIndex)
@@ -486,7 +797,7 @@ func (m *PaletteManager) getProof(e *CrossTransfer, height uint64) (proof []byte
 	heightHex := uint64ToHex(height)
 
 	// get proof from palette chain
-	proof, err = palette.GetProof(m.eccdContract(), proofKey, heightHex)
+	proof, err = m.paletteClient.GetProof(m.eccdContract(), proofKey, heightHex)
 	if err != nil {
 		return
 	}
@@ -497,6 +808,13 @@ func (m *PaletteManager) getProof(e *CrossTransfer, height uint64) (proof []byte
 		return
 	}
 
+	// refuse to submit proof which can not be verified with the block header.
+	eccd := pltcm.HexToAddress(m.eccdContract())
+	if err = verifyProof(proof, block.Root(), eccd, keyBytes, e.value); err != nil {
+		err = fmt.Errorf("invalid proof of tx %s on height %d, err: %s", txIdHex(e.txId), height, err)
+		return
+	}
+
 	hdr, err = block.Header().MarshalJSON()
 	return
 }
@@ -514,7 +832,7 @@ func (m *PaletteManager) commitProof(
 
 	sideChainId := m.sideChainID()
 	relayAddr := pltcm.Hex2Bytes(m.polySigner.Address.ToHexString())
-	tx, err := m.polySdk.Native.Ccm.ImportOuterTransfer(
+	tx, err := m.polySdk.ImportOuterTransfer(
 		sideChainId,
 		txData,
 		height,
@@ -543,19 +861,34 @@ func (m *PaletteManager) commitProof(
 	return tx.ToHexString(), nil
 }
 
+// checkLockEvents check at most `CheckBatchSize` poly transactions in `check` bucket in one round, and
+// the next round resumes after the last checked one, so that all of them are checked in turn.
 func (m *PaletteManager) checkLockEvents() error {
-	checkMap, err := m.db.GetAllCheck()
+	entries, next, err := m.db.ListCheck(m.checkCursor, m.checkBatchSize())
 	if err != nil {
-		return fmt.Errorf("checkLockEvents - m.db.GetAllCheck error: %s", err)
+		return fmt.Errorf("checkLockEvents - m.db.ListCheck error: %s", err)
 	}
+	m.checkCursor = next
+	m.logBacklog()
+
+	for _, entry := range entries {
+		txhash := hex.EncodeToString(entry.Key)
+		record, err := decodeCheckRecord(entry.Value)
+		if err != nil {
+			log.Errorf("PaletteManager checkLockEvents - decode check record of poly tx %s error: %s", txhash, err)
+			continue
+		}
+		v := record.crossTx
 
-	for txhash, v := range checkMap {
 		event, err := m.polySdk.GetSmartContractEvent(txhash)
 		if err != nil {
 			log.Errorf("PaletteManager checkLockEvents - m.aliaSdk.GetSmartContractEvent error: %s", err)
 			continue
 		}
 		if event == nil {
+			if m.checkExpired(txhash, record) {
+				m.checkTimedOut(txhash, v)
+			}
 			continue
 		}
 
@@ -563,6 +896,8 @@ func (m *PaletteManager) checkLockEvents() error {
 			log.Errorf("PaletteManager checkLockEvents - state of poly tx %s is failed", txhash)
 			if err := m.db.PutRetry(v); err != nil {
 				log.Errorf("PaletteManager checkLockEvents - m.db.PutRetry error:%s", err)
+			} else {
+				m.retryFailed(v, fmt.Errorf("state of poly tx %s is failed", txhash))
 			}
 		}
 
@@ -606,6 +941,12 @@ func (m *PaletteManager) eccmContract() string {
 	return m.config.PaletteConfig.ECCMContractAddress
 }
 
+// defaultCheckBatchSize is used if `PaletteConfig.CheckBatchSize` is not configured.
+const defaultCheckBatchSize = 1000
+
+// defaultHeaderCommitTimeout is used if `PaletteConfig.HeaderCommitTimeout` is not configured.
+const defaultHeaderCommitTimeout = 120 * time.Second
+
 // usually add/del single node need 4 blocks, and relayer should waiting for at least 1 block to avoid palette chain fork.
 const defaultDistance = 6
 
@@ -617,6 +958,47 @@ func (m *PaletteManager) safeBlockDistance() uint64 {
 	}
 }
 
+// prefetchWindow return the max number of blocks fetched ahead of current sync height.
+func (m *PaletteManager) prefetchWindow() int {
+	if m.config.PaletteConfig.PrefetchWindow <= 0 {
+		return defaultPrefetchWindow
+	}
+	return m.config.PaletteConfig.PrefetchWindow
+}
+
+// filterBlockRange return the max number of blocks whose cross chain events are filtered in one request.
+func (m *PaletteManager) filterBlockRange() uint64 {
+	if m.config.PaletteConfig.FilterBlockRange <= 0 {
+		return defaultFilterBlockRange
+	}
+	return uint64(m.config.PaletteConfig.FilterBlockRange)
+}
+
+// headerCommitTimeout return the max duration for waiting header commitment confirmed on poly chain.
+func (m *PaletteManager) headerCommitTimeout() time.Duration {
+	if m.config.PaletteConfig.HeaderCommitTimeout <= 0 {
+		return defaultHeaderCommitTimeout
+	}
+	return time.Duration(m.config.PaletteConfig.HeaderCommitTimeout) * time.Second
+}
+
+// checkBatchSize return the max number of poly transactions checked in one round, and the max number of
+// cross transfers listed from `retry` bucket at a time.
+func (m *PaletteManager) checkBatchSize() int {
+	if m.config.PaletteConfig.CheckBatchSize <= 0 {
+		return defaultCheckBatchSize
+	}
+	return m.config.PaletteConfig.CheckBatchSize
+}
+
+// headersPerBatch return the max number of epoch headers synced to poly chain in one transaction.
+func (m *PaletteManager) headersPerBatch() int {
+	if m.config.PaletteConfig.HeadersPerBatch <= 0 {
+		return 1
+	}
+	return m.config.PaletteConfig.HeadersPerBatch
+}
+
 func (m *PaletteManager) sideChainID() uint64 {
 	return m.config.PaletteConfig.SideChainId
 }
@@ -3,11 +3,13 @@ package manager
 import (
 	"encoding/binary"
 	"testing"
+	"time"
 
 	pltcm "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLittleEndian(t *testing.T) {
@@ -24,6 +26,8 @@ func TestLittleEndian(t *testing.T) {
 
 // TestPLTGetChainIDFromPolyChain get paletteClient identity
 func TestPLTGetChainIDFromPolyChain(t *testing.T) {
+	requireLive(t)
+
 	chainID, err := testPolySdk.GetNetworkId()
 	assert.NoError(t, err)
 
@@ -34,6 +38,8 @@ func TestPLTGetChainIDFromPolyChain(t *testing.T) {
 // header should be expect hex string, and header extra validators should
 // contain 8 addresses
 func TestPLTHandlerBlockHeader(t *testing.T) {
+	requireLive(t)
+
 	debugAddAndDelValidator := true
 
 	blockHeightList := []uint64{4197}
@@ -107,6 +113,8 @@ func TestPLTHandlerBlockHeader(t *testing.T) {
 // TestPLTFetchBlockEvent fetch block event and put into blot db,
 // and `retry` bucket length should be more than 0.
 func TestPLTFetchBlockEvent(t *testing.T) {
+	requireLive(t)
+
 	var height uint64 = 4197
 
 	succeed := testPLTMgr.fetchLockEvents(height)
@@ -119,17 +127,26 @@ func TestPLTFetchBlockEvent(t *testing.T) {
 }
 
 func TestPLTCommitHeader(t *testing.T) {
+	requireLive(t)
+
 	var height uint64 = 5146
 
 	assert.True(t, testPLTMgr.fetchBlockHeader(height))
 	assert.True(t, len(testPLTMgr.curHeader.valset) > 0)
 
-	assert.True(t, testPLTMgr.commitHeader())
+	testPLTMgr.pendingHeaders = append(testPLTMgr.pendingHeaders, testPLTMgr.curHeader)
+	assert.True(t, testPLTMgr.commitHeaders())
+	for testPLTMgr.inflight != nil {
+		time.Sleep(1 * time.Second)
+		testPLTMgr.commitHeaders()
+	}
 	assert.Equal(t, height, testPLTMgr.lastEpoch.height)
 }
 
 // TestPLTFindLatestHeight get synced header height
 func TestPLTFindLatestHeight(t *testing.T) {
+	requireLive(t)
+
 	height := testPLTMgr.findLastEpochHeight()
 	t.Logf("poly chain synced to block %d", height)
 
@@ -141,14 +158,22 @@ func TestPLTFindLatestHeight(t *testing.T) {
 }
 
 func TestPLTHandleNewBlocks(t *testing.T) {
+	requireLive(t)
+
 	var blockStart, blockEnd uint64 = 6567, 6569
 
-	for i := blockStart; i <= blockEnd; i++ {
-		testPLTMgr.handleNewBlock(i)
+	quit := make(chan struct{})
+	defer close(quit)
+	for result := range testPLTMgr.prefetchBlocks(blockStart, blockEnd+1, quit) {
+		blk := <-result
+		require.NoError(t, blk.err)
+		testPLTMgr.handleNewBlock(blk)
 	}
 }
 
 func TestPLTBucketCrossTx(t *testing.T) {
+	requireLive(t)
+
 	retryList, err := testPLTMgr.db.GetAllRetry()
 	assert.NoError(t, err)
 
@@ -163,6 +188,8 @@ func TestPLTBucketCrossTx(t *testing.T) {
 
 // get `raw` from bolt bucket
 func TestPLTCommitProof(t *testing.T) {
+	requireLive(t)
+
 	var (
 		height uint64 = 14420
 		raw           = "0x023033200043f645f9be7bba122c2e1322fcacb042a2bb5a4b66dd2b0b3a482e7b212ae8c62000000000000000000000000000000000000000000000000000000000000000032069968925c79a08f4f9bd08cb1361db48dea29b431d57736831c0239728ec4b83140000000000000000000000000000000000000103650000000000000014000000000000000000000000000000000000010306756e6c6f636b4a140000000000000000000000000000000000000103145593b2b8dc63d0ed68aa8f885707b2dc5787e391000064a7b3b6e00d00000000000000000000000000000000000000000000000065000000f135000000000000"
@@ -22,11 +22,11 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
-	"math/rand"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -41,7 +41,6 @@ import (
 	"github.com/palettechain/palette-relayer/db"
 	"github.com/palettechain/palette-relayer/log"
 	"github.com/palettechain/palette-relayer/utils/common"
-	"github.com/palettechain/palette-relayer/utils/keystore"
 	"github.com/palettechain/palette-relayer/utils/nonce"
 	"github.com/polynetwork/eth-contracts/go_abi/eccd_abi"
 	"github.com/polynetwork/eth-contracts/go_abi/eccm_abi"
@@ -53,22 +52,40 @@ import (
 	crosscm "github.com/polynetwork/poly/native/service/cross_chain_manager/common"
 )
 
+var (
+	// ErrTxReverted is returned if the relayed palette transaction is mined but failed.
+	ErrTxReverted = errors.New("palette transaction reverted")
+
+	// ErrTxConfirmTimeout is returned if the relayed palette transaction is not confirmed before deadline.
+	ErrTxConfirmTimeout = errors.New("palette transaction not confirmed before deadline")
+
+	// ErrTxAborted is returned if sending is aborted before the relayed palette transaction confirmed.
+	ErrTxAborted = errors.New("palette transaction sending aborted")
+)
+
 const (
 	ChanLen = 64
+
+	// defaultDrainTimeout is used if `ServiceConfig.DrainTimeout` is not configured.
+	defaultDrainTimeout = 30 * time.Second
 )
 
 type PolyManager struct {
 	config *config.ServiceConfig
 	db     *db.BoltDB
 
-	polySdk    *sdk.PolySdk
-	paletteCli *pltcli.Client
+	polySdk    PolyClient
+	paletteCli PaletteTxClient
 	senders    []*PaletteSender
-	eccd       *eccd_abi.EthCrossChainData // palette eccd contract
+	selector   senderSelector
+	eccd       CrossChainData // palette eccd contract
 
 	currentHeight uint32
 
-	exitChan chan int
+	// routines started by `Start`.
+	wg sync.WaitGroup
+	// cancelSend aborts sending palette transactions if shutdown takes longer than drain timeout.
+	cancelSend context.CancelFunc
 }
 
 // 从poly到palette
@@ -80,66 +97,98 @@ func NewPolyManager(
 	boltDB *db.BoltDB,
 ) (*PolyManager, error) {
 
-	reader := strings.NewReader(eccm_abi.EthCrossChainManagerABI)
-	contractABI, err := abi.JSON(reader)
+	chainId, err := pltSDK.ChainID(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
-	chainId, err := pltSDK.ChainID(context.Background())
+	ks, accArr, err := srvCfg.ImportPaletteAccount(chainId)
 	if err != nil {
 		return nil, err
 	}
 
-	ks, accArr, err := srvCfg.ImportPaletteAccount(chainId)
+	eccdAddr := pltcm.HexToAddress(srvCfg.PaletteConfig.ECCDContractAddress)
+	eccd, err := eccd_abi.NewEthCrossChainData(eccdAddr, pltSDK)
+	if err != nil {
+		return nil, fmt.Errorf("NewPolyManager - generate eccd contract err: %s", err)
+	}
+
+	return newPolyManager(srvCfg, polyForceStartBlockHeight, &polyClient{polySDK}, pltSDK, eccd, ks, accArr, boltDB)
+}
+
+// newPolyManager assemble poly manager and palette senders with chain access interfaces,
+// which allows tests to replace palette and poly nodes with in-memory fakes.
+func newPolyManager(
+	srvCfg *config.ServiceConfig,
+	polyForceStartBlockHeight uint32,
+	polySDK PolyClient,
+	pltSDK PaletteTxClient,
+	eccd CrossChainData,
+	ks TxSigner,
+	accArr []accounts.Account,
+	boltDB *db.BoltDB,
+) (*PolyManager, error) {
+
+	if err := validateGasPrice(srvCfg.PaletteConfig); err != nil {
+		return nil, err
+	}
+	selector, err := newSenderSelector(srvCfg.PaletteConfig.SenderStrategy)
 	if err != nil {
 		return nil, err
 	}
+	if err := validateWorkers(srvCfg); err != nil {
+		return nil, err
+	}
 
+	reader := strings.NewReader(eccm_abi.EthCrossChainManagerABI)
+	contractABI, err := abi.JSON(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	sendCtx, cancelSend := context.WithCancel(context.Background())
 	mgr := &PolyManager{
-		exitChan:      make(chan int),
+		cancelSend:    cancelSend,
 		config:        srvCfg,
 		polySdk:       polySDK,
 		currentHeight: polyForceStartBlockHeight,
 		db:            boltDB,
 		paletteCli:    pltSDK,
+		eccd:          eccd,
+		selector:      selector,
 	}
 
 	senders := make([]*PaletteSender, len(accArr))
-	nonceMgr := nonce.NewNonceManager(pltSDK)
-	for i, v := range senders {
-		eccd, err := eccd_abi.NewEthCrossChainData(mgr.eccdContract(), pltSDK)
-		if err != nil {
-			return nil, err
-		}
-
-		v = &PaletteSender{
+	nonceMgr := nonce.NewNonceManager(pltSDK, boltDB)
+	inflight := newTxSet()
+	for i := range senders {
+		senders[i] = &PaletteSender{
 			acc:           accArr[i],
 			paletteClient: pltSDK,
 			keyStore:      ks,
 			config:        srvCfg,
-			polySdk:       polySDK,
 			contractAbi:   &contractABI,
 			nonceManager:  nonceMgr,
-			cmap:          make(map[string]chan *PaletteTxInfo),
+			inflight:      inflight,
+			selectSender:  mgr.selectSender,
+			queue:         make(chan *PaletteTxInfo, mgr.queueLength()),
 			eccd:          eccd,
+			db:            boltDB,
+			ctx:           sendCtx,
 		}
-		senders[i] = v
 	}
 	mgr.senders = senders
+	for _, s := range senders {
+		s.startWorkers(mgr.workerNum())
+	}
 
 	mgr.init()
+	mgr.replayPaletteTxs()
 
 	return mgr, nil
 }
 
 func (m *PolyManager) init() {
-	eccd, err := eccd_abi.NewEthCrossChainData(m.eccdContract(), m.paletteCli)
-	if err != nil {
-		panic(fmt.Sprintf("PolyManager init - generate eccd contract err: %s", err))
-	}
-	m.eccd = eccd
-
 	// current height settle as poly force start height
 	if m.currentHeight > 0 {
 		log.Infof("PolyManager init - start height from flag: %d", m.currentHeight)
@@ -156,8 +205,64 @@ func (m *PolyManager) init() {
 	}
 }
 
-func (m *PolyManager) MonitorChain() {
+// replayPaletteTxs queue palette transactions which are not sent before last shutdown again, and drop the
+// ones which are already executed on palette chain.
+func (m *PolyManager) replayPaletteTxs() {
+	list := make([]*PaletteTxInfo, 0)
+	err := m.db.ForEachPaletteTx(func(k, v []byte) {
+		info := new(PaletteTxInfo)
+		if err := info.Deserialization(polycm.NewZeroCopySource(v)); err != nil {
+			log.Errorf("PolyManager replayPaletteTxs - deserialize palette tx %x err: %s", k, err)
+			return
+		}
+		list = append(list, info)
+	})
+	if err != nil {
+		log.Errorf("PolyManager replayPaletteTxs - m.db.ForEachPaletteTx error: %s", err)
+		return
+	}
+
+	for _, info := range list {
+		if ok, _ := m.eccd.CheckIfFromChainTxExist(nil, info.fromChainID, info.fromTx); ok {
+			log.Infof("PolyManager replayPaletteTxs - poly_tx %s already relayed", info.polyTxHash)
+			if err := m.db.DeletePaletteTx(info.key()); err != nil {
+				log.Errorf("PolyManager replayPaletteTxs - m.db.DeletePaletteTx error: %s", err)
+			}
+			continue
+		}
+
+		sender := m.selectSender()
+		if sender == nil {
+			log.Errorf("PolyManager replayPaletteTxs - no sender for poly tx %s, it's kept for next restart",
+				info.polyTxHash)
+			continue
+		}
+		sender.enqueue(info)
+		log.Infof("PolyManager replayPaletteTxs - sender %s is handling poly tx ( hash: %s, height: %d )",
+			sender.acc.Address.String(), info.polyTxHash, info.polyHeight)
+	}
+}
+
+// Start run `MonitorChain`, balance monitor and nonce gap filler in background until `ctx` is done.
+func (m *PolyManager) Start(ctx context.Context) {
+	m.wg.Add(3)
+	go func() {
+		defer m.wg.Done()
+		m.MonitorChain(ctx)
+	}()
+	go func() {
+		defer m.wg.Done()
+		m.monitorBalances(ctx)
+	}()
+	go func() {
+		defer m.wg.Done()
+		m.fillNonceGaps(ctx)
+	}()
+}
+
+func (m *PolyManager) MonitorChain(ctx context.Context) {
 	ticker := time.NewTicker(config.ONT_MONITOR_INTERVAL)
+	defer ticker.Stop()
 
 	for {
 		select {
@@ -176,7 +281,7 @@ func (m *PolyManager) MonitorChain() {
 			}
 			// log.Infof("PolyManager MonitorChain - poly chain current height: %d", latestHeight)
 
-			for ; m.currentHeight <= workHeightEnd; m.currentHeight++ {
+			for ; m.currentHeight <= workHeightEnd && ctx.Err() == nil; m.currentHeight++ {
 				log.Infof("PolyManager MonitorChain - poly chain current height: %d, loop end height %d", m.currentHeight, workHeightEnd)
 				if !m.handleDepositEvents(m.currentHeight) {
 					break
@@ -187,7 +292,7 @@ func (m *PolyManager) MonitorChain() {
 				log.Errorf("PolyManager MonitorChain - failed to save height of poly: %v", err)
 			}
 
-		case <-m.exitChan:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -240,7 +345,15 @@ func (m *PolyManager) handleDepositEvents(height uint32) bool {
 		return false
 	}
 
-	cnt := 0
+	// senders are picked for every deposit before anything is persisted, so a
+	// height which has to be handled again never leaves half of its txs queued.
+	type deposit struct {
+		merkle    *crosscm.ToMerkleValue
+		auditPath []byte
+		txHash    string
+		sender    *PaletteSender
+	}
+	var deposits []*deposit
 	for _, event := range events {
 		for _, notify := range event.Notify {
 			if !m.checkNotifyAddr(notify.ContractAddress) {
@@ -270,18 +383,27 @@ func (m *PolyManager) handleDepositEvents(height uint32) bool {
 				continue
 			}
 
-			cnt++
 			sender := m.selectSender()
-			sender.commitDepositEventsWithHeader(hdr, merkle, hp, anchor, event.TxHash, auditPath)
+			if sender == nil {
+				log.Errorf("PolyManager handleDepositEvents - no sender for poly tx %s at height %d",
+					event.TxHash, height)
+				return false
+			}
+			deposits = append(deposits, &deposit{merkle, auditPath, event.TxHash, sender})
+		}
+	}
 
-			log.Infof("PolyManager sender %s is handling poly tx ( hash: %s, height: %d )",
-				sender.acc.Address.String(), event.TxHash, height)
+	for _, d := range deposits {
+		if !d.sender.commitDepositEventsWithHeader(hdr, d.merkle, hp, anchor, d.txHash, d.auditPath) {
+			return false
 		}
+		log.Infof("PolyManager sender %s is handling poly tx ( hash: %s, height: %d )",
+			d.sender.acc.Address.String(), d.txHash, height)
 	}
 
-	if cnt == 0 && isEpoch && isCurr {
+	if len(deposits) == 0 && isEpoch && isCurr {
 		sender := m.selectSender()
-		return sender.commitHeader(hdr, pubKeyList)
+		return sender != nil && sender.commitHeader(hdr, pubKeyList)
 	}
 
 	return true
@@ -343,34 +465,67 @@ func (m *PolyManager) getProofWithNotify(height uint32, notify *polysdkcm.Notify
 	return proof
 }
 
-func (m *PolyManager) selectSender() *PaletteSender {
-	if len(m.senders) == 1 {
-		return m.senders[0]
-	}
-
-	idx := rand.Intn(len(m.senders))
-	return m.senders[idx]
-}
-
+// Stop wait for `MonitorChain` exit and palette transactions in queue sent, it should be called after the
+// context is done. sending is aborted if shutdown takes longer than drain timeout, and nonces of transactions
+// which are not broadcast yet are returned to nonce manager.
 func (m *PolyManager) Stop() {
-	m.exitChan <- 1
-	close(m.exitChan)
+	timeout := m.drainTimeout()
+	timer := time.AfterFunc(timeout, func() {
+		log.Errorf("PolyManager Stop - drain timeout %s exceeded, abort sending palette transactions", timeout)
+		m.cancelSend()
+	})
+	defer timer.Stop()
+
+	m.wg.Wait()
+	for _, s := range m.senders {
+		s.stop()
+	}
+	m.cancelSend()
 	log.Infof("poly chain manager exit.")
 }
 
+func (m *PolyManager) drainTimeout() time.Duration {
+	if m.config.DrainTimeout <= 0 {
+		return defaultDrainTimeout
+	}
+	return time.Duration(m.config.DrainTimeout) * time.Second
+}
+
 type PaletteSender struct {
+	// accessed atomically, keep them at the head of struct for 64-bit alignment.
+	pending        int64
+	unhealthyUntil int64
+	blocked        uint64
+	failures       int32
+
 	acc           accounts.Account
-	keyStore      *keystore.PaletteKeyStore
-	cmap          map[string]chan *PaletteTxInfo
+	keyStore      TxSigner
+	queue         chan *PaletteTxInfo
 	nonceManager  *nonce.NonceManager
-	paletteClient *pltcli.Client
-	polySdk       *sdk.PolySdk
+	inflight      *txSet
+	selectSender  func() *PaletteSender
+	paletteClient PaletteTxClient
 	config        *config.ServiceConfig
 	contractAbi   *abi.ABI
-	eccd          *eccd_abi.EthCrossChainData
+	eccd          CrossChainData
+	db            *db.BoltDB
+
+	// balance polled by balance monitor.
+	balanceMtx   sync.RWMutex
+	balance      *big.Int
+	balanceTime  time.Time
+	balanceLevel int
+
+	// ctx is cancelled if shutdown takes longer than drain timeout.
+	ctx context.Context
+	// workers consuming `queue`.
+	wg sync.WaitGroup
+	// broadcastMtx keeps workers broadcasting transactions in order of nonces.
+	broadcastMtx sync.Mutex
 }
 
-// commitDepositEventsWithHeader
+// commitDepositEventsWithHeader queue the palette transaction which relays poly transaction `polyTxHash`, and
+// the transaction is persisted before queued, so that it's never lost once poly height checkpointed.
 func (s *PaletteSender) commitDepositEventsWithHeader(
 	header *polytypes.Header,
 	param *crosscm.ToMerkleValue,
@@ -429,29 +584,23 @@ func (s *PaletteSender) commitDepositEventsWithHeader(
 		return false
 	}
 
-	// 并发发交易
-	k := s.getRouter()
-	c, ok := s.cmap[k]
-	if !ok {
-		c = make(chan *PaletteTxInfo, ChanLen)
-		s.cmap[k] = c
-		go func() {
-			for v := range c {
-				if err = s.sendTxToPalette(v.contractAddr, v.polyTxHash, v.txData); err != nil {
-					log.Errorf("PolyManager - failed to send tx to ethereum: error: %v, txData: %s",
-						err, hex.EncodeToString(v.txData))
-				}
-			}
-		}()
-	}
-
-	c <- &PaletteTxInfo{
+	info := &PaletteTxInfo{
 		txData:       txData,
 		contractAddr: s.eccmContract(),
-		gasPrice:     paletteTxGasPrice,
 		gasLimit:     paletteGasLimit,
 		polyTxHash:   polyTxHash,
+		polyHeight:   header.Height,
+		fromChainID:  param.FromChainID,
+		fromTx:       fromTx,
+	}
+	sink := polycm.NewZeroCopySink(nil)
+	info.Serialization(sink)
+	if err := s.db.PutPaletteTx(info.key(), sink.Bytes()); err != nil {
+		log.Errorf("PolyManager commitDepositEventsWithHeader - s.db.PutPaletteTx error: %s", err)
+		return false
 	}
+
+	s.enqueue(info)
 	return true
 }
 
@@ -467,7 +616,7 @@ func (s *PaletteSender) commitHeader(header *polytypes.Header, pubkList []byte)
 
 	contractAddr := s.eccmContract()
 	polyTxHash := fmt.Sprintf("header: %d", header.Height)
-	if err := s.sendTxToPalette(contractAddr, polyTxHash, txDat); err != nil {
+	if _, err := s.sendTxToPalette(contractAddr, polyTxHash, txDat, nil); err != nil {
 		log.Errorf("PolyManager commitHeader - send transaction error:%s\n", err.Error())
 		return false
 	}
@@ -475,92 +624,209 @@ func (s *PaletteSender) commitHeader(header *polytypes.Header, pubkList []byte)
 	return true
 }
 
+// sendTxToPalette broadcast the transaction and wait until it's confirmed. the gas price is raised to
+// `minGasPrice` if it's lower, and the gas price of the last transaction broadcast is returned, which
+// is nil if nothing is broadcast.
 func (s *PaletteSender) sendTxToPalette(
 	contractAddr pltcm.Address,
 	polyTxHash string,
 	txData []byte,
-) (err error) {
+	minGasPrice *big.Int,
+) (lastGasPrice *big.Int, err error) {
 
-	curNonce := s.nonceManager.UseNonce(s.acc.Address)
+	defer func() {
+		if s.ctx.Err() == nil {
+			s.recordSend(err)
+		}
+	}()
+
+	gasPrice, err := s.gasPrice()
+	if err != nil {
+		log.Errorf("sendTxToPalette - get gas price error: %s", err.Error())
+		return nil, err
+	}
+	if minGasPrice != nil && gasPrice.Cmp(minGasPrice) < 0 {
+		gasPrice = minGasPrice
+	}
 
 	callMsg := ethereum.CallMsg{
-		From: s.acc.Address, To: &contractAddr, Gas: 0, GasPrice: paletteTxGasPrice,
+		From: s.acc.Address, To: &contractAddr, Gas: 0, GasPrice: gasPrice,
 		Value: big.NewInt(0), Data: txData,
 	}
-	gasLimit, err := s.paletteClient.EstimateGas(context.Background(), callMsg)
+	gasLimit, err := s.paletteClient.EstimateGas(s.ctx, callMsg)
 	if err != nil {
 		log.Errorf("sendTxToPalette - estimate gas limit error: %s", err.Error())
-		return err
+		return nil, err
+	}
+
+	signedTx, err := s.broadcastTx(contractAddr, gasLimit, gasPrice, txData)
+	if err != nil {
+		return nil, err
 	}
 
+	curNonce := signedTx.Nonce()
+	signedTx, err = s.waitTransactionConfirm(polyTxHash, signedTx)
+	switch {
+	case err == nil || errors.Is(err, ErrTxReverted):
+		s.nonceManager.ConfirmNonce(s.acc.Address, curNonce)
+	case errors.Is(err, ErrTxConfirmTimeout):
+		// the transaction may never be mined, so the nonce is returned to be reused by the next
+		// transaction, or filled as a gap if higher nonces are in flight.
+		s.nonceManager.ReturnNonce(s.acc.Address, curNonce)
+	}
+	hash := signedTx.Hash()
+	url := common.GetExplorerUrl(s.keyStore.GetChainId()) + hash.String()
+	logInf := fmt.Sprintf(" to relay tx to ethereum: (eth_hash: %s, sender: %s, curNonce: %d, gas_price: %s, "+
+		"poly_hash: %s, eth_explorer: %s)", hash.String(), s.acc.Address.Hex(), curNonce, signedTx.GasPrice(),
+		polyTxHash, url)
+
+	if err == nil {
+		log.Infof("PolyManager - successful %s", logInf)
+	} else {
+		log.Errorf("PolyManager - failed %s, err: %v", logInf, err)
+	}
+
+	return signedTx.GasPrice(), err
+}
+
+// broadcastTx sign and broadcast the palette transaction. workers of sender broadcast transactions one by one,
+// and the nonce is assigned right before broadcasting, so that nonces are consumed in the order they are
+// broadcast. the nonce is returned if the transaction is not broadcast.
+func (s *PaletteSender) broadcastTx(
+	contractAddr pltcm.Address,
+	gasLimit uint64,
+	gasPrice *big.Int,
+	txData []byte,
+) (signedTx *types.Transaction, err error) {
+
+	s.broadcastMtx.Lock()
+	defer s.broadcastMtx.Unlock()
+
+	curNonce := s.nonceManager.UseNonce(s.acc.Address)
+	defer func() {
+		if err == nil {
+			return
+		}
+		s.nonceManager.ReturnNonce(s.acc.Address, curNonce)
+		if nonce.IsNonceError(err) {
+			log.Warnf("sendTxToPalette - nonce %d of sender %s rejected, resync nonce from chain",
+				curNonce, s.acc.Address.Hex())
+			s.nonceManager.Resync(s.acc.Address)
+		}
+	}()
+
 	tx := types.NewTransaction(
 		curNonce,
 		contractAddr,
 		paletteTxValue,
 		gasLimit,
-		paletteTxGasPrice,
+		gasPrice,
 		txData,
 	)
 
-	defer func() {
-		if err != nil {
-			s.nonceManager.ReturnNonce(s.acc.Address, curNonce)
-		}
-	}()
-
-	var signedTx *types.Transaction
 	if signedTx, err = s.keyStore.SignTransaction(tx, s.acc); err != nil {
 		err = fmt.Errorf("PolyManager commitDepositEventsWithHeader - sign raw tx error and return curNonce %d: %v",
 			curNonce, err)
 		return
 	}
 
-	if err = s.paletteClient.SendTransaction(context.Background(), signedTx, bind.PrivateTxArgs{}); err != nil {
-		err = fmt.Errorf("PolyManager commitDepositEventsWithHeader - send transaction error and return curNonce %d: %v",
+	if err = s.ctx.Err(); err != nil {
+		err = fmt.Errorf("PolyManager commitDepositEventsWithHeader - sending aborted and return curNonce %d: %v",
 			curNonce, err)
 		return
 	}
-
-	hash := signedTx.Hash()
-	url := common.GetExplorerUrl(s.keyStore.GetChainId()) + hash.String()
-	logInf := fmt.Sprintf(" to relay tx to ethereum: (eth_hash: %s, sender: %s, curNonce: %d, "+
-		"poly_hash: %s, eth_explorer: %s)", hash.String(), s.acc.Address.Hex(), curNonce, polyTxHash, url)
-
-	if s.waitTransactionConfirm(polyTxHash, hash) {
-		log.Infof("PolyManager - successful %s", logInf)
-	} else {
-		log.Errorf("PolyManager - failed %s", logInf)
+	if err = s.paletteClient.SendTransaction(s.ctx, signedTx, bind.PrivateTxArgs{}); err != nil {
+		err = fmt.Errorf("PolyManager commitDepositEventsWithHeader - send transaction error and return curNonce %d: %v",
+			curNonce, err)
+		return
 	}
-
 	return
 }
 
-func (s *PaletteSender) waitTransactionConfirm(polyTxHash string, hash pltcm.Hash) bool {
+// waitTransactionConfirm return the mined one of transaction `tx` and its replacements once it's confirmed by
+// `PaletteConfig.Confirmations` blocks on canonical chain. the transaction is replaced with the same nonce and
+// bumped gas price if it's pending longer than `PaletteConfig.PendingTimeout`. `ErrTxReverted`,
+// `ErrTxConfirmTimeout` or `ErrTxAborted` is returned if the transaction is not confirmed as success.
+func (s *PaletteSender) waitTransactionConfirm(polyTxHash string, tx *types.Transaction) (*types.Transaction, error) {
+	sent := []*types.Transaction{tx}
+	lastSent := time.Now()
+	deadline := time.After(s.confirmTimeout())
 	for {
-		time.Sleep(time.Second * 2)
-
-		_, pending, err := s.paletteClient.TransactionByHash(context.Background(), hash)
-		if err != nil {
-			continue
+		select {
+		case <-time.After(s.txPollInterval()):
+		case <-deadline:
+			return sent[len(sent)-1], fmt.Errorf("%w: poly_tx %s, %d transactions sent in %s",
+				ErrTxConfirmTimeout, polyTxHash, len(sent), s.confirmTimeout())
+		case <-s.ctx.Done():
+			log.Warnf("PolyManager - stop waiting ( eth_transaction %s, poly_tx %s ), sending aborted",
+				tx.Hash().String(), polyTxHash)
+			return sent[len(sent)-1], fmt.Errorf("%w: %v", ErrTxAborted, s.ctx.Err())
 		}
 
-		if pending {
-			log.Infof("PolyManager - ( eth_transaction %s, poly_tx %s ) is pending: %v",
-				hash.String(), polyTxHash, pending)
-			continue
+		mined, receipt := s.minedTransaction(sent)
+		if mined != nil {
+			if !s.receiptConfirmed(polyTxHash, receipt) {
+				continue
+			}
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return mined, fmt.Errorf("%w: eth_transaction %s in block %d", ErrTxReverted,
+					mined.Hash().String(), receipt.BlockNumber)
+			}
+			return mined, nil
 		}
 
-		receipt, err := s.paletteClient.TransactionReceipt(context.Background(), hash)
-		if err != nil {
+		latest := sent[len(sent)-1]
+		log.Infof("PolyManager - ( eth_transaction %s, poly_tx %s ) is pending", latest.Hash().String(), polyTxHash)
+		if time.Since(lastSent) < s.pendingTimeout() || len(sent)-1 >= s.maxReplacements() {
 			continue
 		}
+		lastSent = time.Now()
+		if replacement := s.replaceTransaction(polyTxHash, latest); replacement != nil {
+			sent = append(sent, replacement)
+		}
+	}
+}
 
-		return receipt.Status == types.ReceiptStatusSuccessful
+// minedTransaction return the transaction which has receipt in `sent`, and nil if all of them are pending.
+func (s *PaletteSender) minedTransaction(sent []*types.Transaction) (*types.Transaction, *types.Receipt) {
+	for _, tx := range sent {
+		receipt, err := s.paletteClient.TransactionReceipt(s.ctx, tx.Hash())
+		if err == nil {
+			return tx, receipt
+		}
+		if err != ethereum.NotFound {
+			log.Warnf("PolyManager - get receipt of eth_transaction %s error: %v", tx.Hash().String(), err)
+		}
 	}
+	return nil, nil
 }
 
-func (s *PaletteSender) getRouter() string {
-	return strconv.FormatInt(rand.Int63n(s.config.RoutineNum), 10)
+// receiptConfirmed return true if block of the receipt is still on canonical chain, and it's followed
+// by enough blocks.
+func (s *PaletteSender) receiptConfirmed(polyTxHash string, receipt *types.Receipt) bool {
+	hdr, err := s.paletteClient.HeaderByNumber(s.ctx, receipt.BlockNumber)
+	if err != nil && err != ethereum.NotFound {
+		log.Warnf("PolyManager - get header %d error: %v", receipt.BlockNumber, err)
+		return false
+	}
+	if hdr == nil || hdr.Hash() != receipt.BlockHash {
+		log.Warnf("PolyManager - block %s of ( eth_transaction %s, poly_tx %s ) is not canonical any more",
+			receipt.BlockHash.Hex(), receipt.TxHash.String(), polyTxHash)
+		return false
+	}
+
+	head, err := s.paletteClient.HeaderByNumber(s.ctx, nil)
+	if err != nil {
+		log.Warnf("PolyManager - get latest header error: %v", err)
+		return false
+	}
+	confirmations := new(big.Int).Sub(head.Number, receipt.BlockNumber).Int64() + 1
+	if confirmations < int64(s.confirmations()) {
+		log.Infof("PolyManager - ( eth_transaction %s, poly_tx %s ) is confirmed by %d blocks, %d required",
+			receipt.TxHash.String(), polyTxHash, confirmations, s.confirmations())
+		return false
+	}
+	return true
 }
 
 func (s *PaletteSender) getExploreUrl(txHash pltcm.Hash) string {
@@ -1,8 +1,6 @@
 package manager
 
 import (
-	"math/rand"
-	"strconv"
 	"strings"
 	"testing"
 
@@ -11,6 +9,7 @@ import (
 	nvcm "github.com/ethereum/go-ethereum/contracts/native/common"
 	"github.com/ethereum/go-ethereum/contracts/native/plt"
 	"github.com/ethereum/go-ethereum/contracts/native/utils"
+	"github.com/palettechain/palette-relayer/config"
 	"github.com/polynetwork/poly/common"
 	polycm "github.com/polynetwork/poly/common"
 	"github.com/polynetwork/poly/core/signature"
@@ -18,25 +17,31 @@ import (
 	"github.com/polynetwork/poly/merkle"
 	crscm "github.com/polynetwork/poly/native/service/cross_chain_manager/common"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestGetRouterNumber(t *testing.T) {
-	var (
-		routineNo int64 = 64
-		cnt             = 10
-	)
-	for i := 0; i < cnt; i++ {
-		no := strconv.FormatInt(rand.Int63n(routineNo), 10)
-		t.Logf("routine num %s", no)
-	}
+func TestWorkerNum(t *testing.T) {
+	cfg := &config.ServiceConfig{RoutineNum: 0}
+	require.NoError(t, validateWorkers(cfg))
+	mgr := &PolyManager{config: cfg}
+	assert.Equal(t, defaultRoutineNum, mgr.workerNum())
+	assert.Equal(t, ChanLen, mgr.queueLength())
+
+	cfg.RoutineNum = 8
+	require.NoError(t, validateWorkers(cfg))
+	assert.Equal(t, 8, mgr.workerNum())
 }
 
 func TestPolyFindLastEpochHeight(t *testing.T) {
+	requireLive(t)
+
 	height := testPolyMgr.findLastEpochHeight()
 	t.Logf("poly last pltEpoch height %d", height)
 }
 
 func TestPolyGenesisBookKeepers(t *testing.T) {
+	requireLive(t)
+
 	hdr, err := testPolyMgr.polySdk.GetHeaderByHeight(1)
 	assert.NoError(t, err)
 
@@ -47,6 +52,8 @@ func TestPolyGenesisBookKeepers(t *testing.T) {
 }
 
 func TestPolyGetLastEpochPubKeyBytes(t *testing.T) {
+	requireLive(t)
+
 	expect := []string{
 		"AaodCegA3EWhwd5hRdcKASGnJCPd3RJ3A5",
 		"AUd2CBoLZkRN2NwKbCZN2CXEbaFS8Y2jso",
@@ -76,6 +83,8 @@ func TestPolyGetLastEpochPubKeyBytes(t *testing.T) {
 }
 
 func TestPolyGetHeader(t *testing.T) {
+	requireLive(t)
+
 	var height uint32 = 1
 	header, err := testPolyMgr.polySdk.GetHeaderByHeight(height)
 	assert.NoError(t, err)
@@ -84,6 +93,8 @@ func TestPolyGetHeader(t *testing.T) {
 }
 
 func TestGetMerkleProof(t *testing.T) {
+	requireLive(t)
+
 	var (
 		height           uint32 = 1
 		anchorHeightList        = []uint32{1}
@@ -98,6 +109,8 @@ func TestGetMerkleProof(t *testing.T) {
 }
 
 func TestPolyCurrentHeight(t *testing.T) {
+	requireLive(t)
+
 	height, err := testPolyMgr.polySdk.GetCurrentBlockHeight()
 	assert.NoError(t, err)
 
@@ -105,6 +118,8 @@ func TestPolyCurrentHeight(t *testing.T) {
 }
 
 func TestPolySelectSender(t *testing.T) {
+	requireLive(t)
+
 	sender := testPolyMgr.selectSender()
 	assert.True(t, sender != nil)
 }
@@ -201,6 +216,8 @@ func TestVerifyProof(t *testing.T) {
 }
 
 func TestPolyCommitProof(t *testing.T) {
+	requireLive(t)
+
 	var (
 		auditPath  = "0xef20dc8dc52c8a8f388426822d6f2908667dc83137692e53d1aece82cc149dd12c0e6b0000000000000020000000000000000000000000000000000000000000000000000000000000000020ff375bc7b8c8da9cf5e43ed89bc2822b2921bfa4fc66edb1a2a1893b4ddcc6531400000000000000000000000000000000000001036b0000000000000014000000000000000000000000000000000000010306756e6c6f636b4a140000000000000000000000000000000000000103145593b2b8dc63d0ed68aa8f885707b2dc5787e391000064a7b3b6e00d000000000000000000000000000000000000000000000000"
 		headerData = "0x00000000db056dd1000000000a477ff2da6d87da01cf6dc6406baf3df5c055dd0b87b85d89667868dad09cb400000000000000000000000000000000000000000000000000000000000000000c3b0ca0e1731299f6a4ede160bd7f3cc562b849c5b6b1f7b664c22c8c9e5c2c8a87b41db1bac796e20c378b65fe138cd0a640facf6ed8969773854427c5fcd490f5ea5f2e8307007fe979dd2166b331fd11017b226c6561646572223a312c227672665f76616c7565223a2242432f5938374a6f386a41646158394e4451774f6e4e4d5662704a56324269335a32795177477131706d7961426e67665a69666f54313072396d74614a5672326e6e6356596b74686272376b32364637502f513155354d3d222c227672665f70726f6f66223a22365a582b6f71496e2f7a5955547377396348654138765855435769346f50366b784d6c2f4661724c35463631702b536661377550636c717043566d696a464d593933322b315a4e6369384e6e7930766a316c784a6c773d3d222c226c6173745f636f6e6669675f626c6f636b5f6e756d223a3437303536352c226e65775f636861696e5f636f6e666967223a6e756c6c7d0000000000000000000000000000000000000000"
@@ -234,10 +251,11 @@ func TestPolyCommitProof(t *testing.T) {
 	//	polyTxHash:   polyTxHash,
 	//}
 
-	err = sender.sendTxToPalette(
+	_, err = sender.sendTxToPalette(
 		sender.eccmContract(),
 		"test",
 		txData,
+		nil,
 	)
 	assert.NoError(t, err)
 }
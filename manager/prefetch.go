@@ -0,0 +1,176 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	plttyp "github.com/ethereum/go-ethereum/core/types"
+	"github.com/palettechain/palette-relayer/log"
+	"github.com/polynetwork/eth-contracts/go_abi/eccm_abi"
+)
+
+const (
+	// defaultPrefetchWindow is used if `PaletteConfig.PrefetchWindow` is not configured.
+	defaultPrefetchWindow = 16
+
+	// defaultFilterBlockRange is used if `PaletteConfig.FilterBlockRange` is not configured.
+	defaultFilterBlockRange = 100
+)
+
+// pltBlock is the content of palette block needed by `PaletteManager`, `err` is set if the header
+// or cross chain events can not be fetched.
+type pltBlock struct {
+	height uint64
+	header *plttyp.Header
+	events []*eccm_abi.EthCrossChainManagerCrossChainEvent
+	err    error
+}
+
+// eventRange is the cross chain events filtered in block range [from, to] and grouped by
+// block number, `done` is closed after filtering finished.
+type eventRange struct {
+	from, to uint64
+	events   map[uint64][]*eccm_abi.EthCrossChainManagerCrossChainEvent
+	err      error
+	done     chan struct{}
+}
+
+// prefetchBlocks fetch blocks in range [start, end) concurrently, at most `PrefetchWindow` blocks are fetched
+// ahead of the consumer. results are delivered in ascending order of height, and each of them is a channel
+// which is filled once the block fetched. prefetching stops as soon as `quit` closed.
+// while catching up, cross chain events are filtered over ranges of `FilterBlockRange` blocks rather than
+// block by block, and the range shrinks to a single block once the manager reaches the chain head.
+func (m *PaletteManager) prefetchBlocks(start, end uint64, quit <-chan struct{}) <-chan chan *pltBlock {
+	queue := make(chan chan *pltBlock, m.prefetchWindow())
+
+	go func() {
+		defer close(queue)
+
+		var rng *eventRange
+		for height := start; height < end; height++ {
+			if rng == nil || height > rng.to {
+				to := height + m.filterBlockRange() - 1
+				if to >= end {
+					to = end - 1
+				}
+				rng = m.filterEventRange(height, to)
+			}
+
+			result := make(chan *pltBlock, 1)
+			select {
+			case queue <- result:
+			case <-quit:
+				return
+			}
+
+			go func(height uint64, rng *eventRange) {
+				result <- m.fetchBlockInRange(height, rng)
+			}(height, rng)
+		}
+	}()
+
+	return queue
+}
+
+// fetchBlockInRange get block header from palette chain, and pick up cross chain events of the block from
+// the filtered range. events must be emitted in the fetched block, otherwise the block was reorganized
+// during fetching and it should be fetched again.
+func (m *PaletteManager) fetchBlockInRange(height uint64, rng *eventRange) *pltBlock {
+	blk := &pltBlock{height: height}
+	blk.header, blk.err = m.paletteClient.HeaderByNumber(context.Background(), uint64ToBig(height))
+	if blk.err != nil {
+		return blk
+	}
+
+	// a failed range fails all of blocks in it, so that their events will never be skipped.
+	<-rng.done
+	if rng.err != nil {
+		blk.err = fmt.Errorf("filter cross chain events in [%d, %d] err: %s", rng.from, rng.to, rng.err)
+		return blk
+	}
+	blk.events = rng.events[height]
+	for _, evt := range blk.events {
+		if evt.Raw.BlockHash != blk.header.Hash() {
+			blk.err = fmt.Errorf("event %s emitted in block %s, but hash of block %d is %s", evt.Raw.TxHash.Hex(),
+				evt.Raw.BlockHash.Hex(), height, blk.header.Hash().Hex())
+			return blk
+		}
+	}
+	return blk
+}
+
+// filterEventRange start filtering cross chain events in block range [from, to] in background.
+func (m *PaletteManager) filterEventRange(from, to uint64) *eventRange {
+	rng := &eventRange{from: from, to: to, done: make(chan struct{})}
+
+	go func() {
+		defer close(rng.done)
+
+		events, err := m.filterCrossChainEvents(from, to)
+		if err != nil {
+			rng.err = err
+			return
+		}
+		rng.events = make(map[uint64][]*eccm_abi.EthCrossChainManagerCrossChainEvent)
+		for _, evt := range events {
+			height := evt.Raw.BlockNumber
+			rng.events[height] = append(rng.events[height], evt)
+		}
+	}()
+
+	return rng
+}
+
+// filterCrossChainEvents filter cross chain events in block range [from, to], and the range is split
+// in halves if palette node rejects it as too large.
+func (m *PaletteManager) filterCrossChainEvents(from, to uint64) (
+	[]*eccm_abi.EthCrossChainManagerCrossChainEvent, error) {
+
+	events, err := m.paletteClient.FilterCrossChainEvent(from, to)
+	if err == nil || from == to || !isRangeTooLarge(err) {
+		return events, err
+	}
+
+	mid := from + (to-from)/2
+	log.Warnf("PaletteManager filterCrossChainEvents - block range [%d, %d] rejected, err: %s, "+
+		"split into [%d, %d] and [%d, %d]", from, to, err, from, mid, mid+1, to)
+
+	left, err := m.filterCrossChainEvents(from, mid)
+	if err != nil {
+		return nil, err
+	}
+	right, err := m.filterCrossChainEvents(mid+1, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// isRangeTooLarge return true if palette node refuses to filter logs because of the size of block range
+// or the number of results.
+func isRangeTooLarge(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, v := range []string{"too large", "too wide", "too many", "more than", "exceed", "limit"} {
+		if strings.Contains(msg, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRangeTooLarge(t *testing.T) {
+	cases := []struct {
+		err    string
+		expect bool
+	}{
+		{"query returned more than 10000 results", true},
+		{"exceed maximum block range: 5000", true},
+		{"block range is too wide", true},
+		{"Log response size exceeded", true},
+		{"connection refused", false},
+		{"header not found", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expect, isRangeTooLarge(fmt.Errorf(c.err)), c.err)
+	}
+}
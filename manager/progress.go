@@ -0,0 +1,104 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import "sync"
+
+// progress is the height reached by a stage of palette manager, e.g., blocks synced by `MonitorChain`
+// or deposits handled by `MonitorDeposit`. it is safe for concurrent use, and subscribers are woken up
+// whenever the height changes, so that the next stage does not need to poll it.
+type progress struct {
+	mtx    sync.Mutex
+	height uint64
+	subs   map[chan struct{}]struct{}
+}
+
+func newProgress(height uint64) *progress {
+	return &progress{
+		height: height,
+		subs:   make(map[chan struct{}]struct{}),
+	}
+}
+
+func (p *progress) get() uint64 {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return p.height
+}
+
+func (p *progress) set(height uint64) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.height != height {
+		p.height = height
+		p.notify()
+	}
+}
+
+// advance increase the height by one if it is still `from`, and return false if the height has been
+// changed by others, e.g., rolled back after palette chain reorganized.
+func (p *progress) advance(from uint64) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.height != from {
+		return false
+	}
+	p.height++
+	p.notify()
+	return true
+}
+
+// lower decrease the height to `height` if it is higher.
+func (p *progress) lower(height uint64) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.height > height {
+		p.height = height
+		p.notify()
+	}
+}
+
+// subscribe return a channel which receives a signal after the height changed, signals are merged
+// if the subscriber is busy. the channel should be released by `unsubscribe`.
+func (p *progress) subscribe() chan struct{} {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	ch := make(chan struct{}, 1)
+	p.subs[ch] = struct{}{}
+	return ch
+}
+
+func (p *progress) unsubscribe(ch chan struct{}) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	delete(p.subs, ch)
+}
+
+func (p *progress) notify() {
+	for ch := range p.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	p := newProgress(10)
+	sub := p.subscribe()
+
+	assert.True(t, p.advance(10))
+	assert.False(t, p.advance(10))
+	p.set(12)
+	assert.Equal(t, uint64(12), p.get())
+
+	// signals are merged while subscriber is busy.
+	<-sub
+	select {
+	case <-sub:
+		t.Fatal("signals should be merged")
+	default:
+	}
+
+	p.lower(13)
+	assert.Equal(t, uint64(12), p.get())
+	p.lower(8)
+	assert.Equal(t, uint64(8), p.get())
+	<-sub
+
+	p.unsubscribe(sub)
+	p.set(20)
+	select {
+	case <-sub:
+		t.Fatal("unsubscribed channel should not be notified")
+	default:
+	}
+}
+
+func TestProgressConcurrent(t *testing.T) {
+	const target = 1000
+	p := newProgress(0)
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 4; i++ {
+		sub := p.subscribe()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer p.unsubscribe(sub)
+			for p.get() < target {
+				<-sub
+			}
+		}()
+	}
+
+	for h := uint64(0); h < target; h++ {
+		require.True(t, p.advance(h))
+	}
+	wg.Wait()
+}
+
+func TestFakePaletteDepositFollowsSync(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr.Start(ctx)
+
+	// blocks are produced while manager is running.
+	const num = 5
+	for i := 0; i < num; i++ {
+		env.emitLockEvent(byte(i + 1))
+		env.palette.Mine(1)
+	}
+	env.palette.Mine(int(mgr.safeBlockDistance()) + 1)
+
+	deadline := time.Now().Add(20 * time.Second)
+	for time.Now().Before(deadline) && len(env.poly.ImportedTransfers()) < num {
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	mgr.Stop()
+
+	assert.Equal(t, num, len(env.poly.ImportedTransfers()))
+	assert.True(t, mgr.depositProgress.get() <= mgr.syncProgress.get())
+}
@@ -0,0 +1,112 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pltcm "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// pltProof is the result of `eth_getProof` returned by palette node.
+type pltProof struct {
+	Address       string            `json:"address"`
+	Balance       string            `json:"balance"`
+	CodeHash      string            `json:"codeHash"`
+	Nonce         string            `json:"nonce"`
+	StorageHash   string            `json:"storageHash"`
+	AccountProof  []string          `json:"accountProof"`
+	StorageProofs []pltStorageProof `json:"storageProof"`
+}
+
+type pltStorageProof struct {
+	Key   string   `json:"key"`
+	Value string   `json:"value"`
+	Proof []string `json:"proof"`
+}
+
+// verifyProof verify the account proof of `contract` against state root of block header, and the storage
+// proof of `key` against storage hash of the account. the proven value should be keccak256 of cross chain
+// raw data, which is recorded in ECCD contract and checked by poly chain in the same way.
+func verifyProof(raw []byte, root pltcm.Hash, contract pltcm.Address, key []byte, value []byte) error {
+	proof := new(pltProof)
+	if err := json.Unmarshal(raw, proof); err != nil {
+		return fmt.Errorf("unmarshal proof err: %s", err)
+	}
+	if pltcm.HexToAddress(proof.Address) != contract {
+		return fmt.Errorf("proof of contract %s expected, got %s", contract.Hex(), proof.Address)
+	}
+	if len(proof.StorageProofs) != 1 {
+		return fmt.Errorf("one storage proof expected, got %d", len(proof.StorageProofs))
+	}
+
+	// account proof
+	enc, err := verifyMerkleProof(root, crypto.Keccak256(contract.Bytes()), proof.AccountProof)
+	if err != nil {
+		return fmt.Errorf("verify account proof err: %s", err)
+	}
+	if enc == nil {
+		return fmt.Errorf("account proof of %s not exist in state root %s", contract.Hex(), root.Hex())
+	}
+	account := new(state.Account)
+	if err := rlp.DecodeBytes(enc, account); err != nil {
+		return fmt.Errorf("decode account of account proof err: %s", err)
+	}
+	if account.Root != pltcm.HexToHash(proof.StorageHash) {
+		return fmt.Errorf("storage hash %s mismatch account proof, expect %s", proof.StorageHash, account.Root.Hex())
+	}
+
+	// storage proof
+	sp := proof.StorageProofs[0]
+	slot := pltcm.BytesToHash(key)
+	if pltcm.BytesToHash(pltcm.FromHex(sp.Key)) != slot {
+		return fmt.Errorf("storage proof of key %s expected, got %s", slot.Hex(), sp.Key)
+	}
+	enc, err = verifyMerkleProof(account.Root, crypto.Keccak256(slot.Bytes()), sp.Proof)
+	if err != nil {
+		return fmt.Errorf("verify storage proof err: %s", err)
+	}
+	if enc == nil {
+		return fmt.Errorf("storage proof of key %s not exist in storage hash %s", slot.Hex(), account.Root.Hex())
+	}
+	var proven []byte
+	if err := rlp.DecodeBytes(enc, &proven); err != nil {
+		return fmt.Errorf("decode value of storage proof err: %s", err)
+	}
+	if expect := crypto.Keccak256Hash(value); pltcm.BytesToHash(proven) != expect {
+		return fmt.Errorf("value of storage proof is %x, expect %s", proven, expect.Hex())
+	}
+	return nil
+}
+
+// verifyMerkleProof return the value of `key` proven by merkle patricia trie nodes, and nil if the key not exist.
+func verifyMerkleProof(root pltcm.Hash, key []byte, nodes []string) ([]byte, error) {
+	db := memorydb.New()
+	for _, node := range nodes {
+		enc := pltcm.FromHex(node)
+		if err := db.Put(crypto.Keccak256(enc), enc); err != nil {
+			return nil, err
+		}
+	}
+	return trie.VerifyProof(root, key, db)
+}
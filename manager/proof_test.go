@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	pltcm "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyStorageProof(t *testing.T) {
+	env := newFakeEnv(t)
+
+	env.emitLockEvent(1)
+	height, evt := env.emitLockEvent(2)
+	hdr, err := env.palette.HeaderByNumber(context.Background(), uint64ToBig(height))
+	require.NoError(t, err)
+
+	crossTx, _ := serializeCrossTransfer(evt, height)
+	key, err := getMappingKey(crossTx.txIndex)
+	require.NoError(t, err)
+	raw, err := env.palette.GetProof(fakeECCDContract.Hex(), hexutil.Encode(key), uint64ToHex(height))
+	require.NoError(t, err)
+
+	assert.NoError(t, verifyProof(raw, hdr.Root, fakeECCDContract, key, evt.Rawdata))
+	assert.Error(t, verifyProof(raw, hdr.Root, fakeECCDContract, key, []byte("tampered")))
+	assert.Error(t, verifyProof(raw, hdr.Root, fakeProxyContract, key, evt.Rawdata))
+	assert.Error(t, verifyProof(raw, pltcm.Hash{}, fakeECCDContract, key, evt.Rawdata))
+
+	// value is not recorded before the event emitted.
+	prev, err := env.palette.HeaderByNumber(context.Background(), uint64ToBig(height-1))
+	require.NoError(t, err)
+	raw, err = env.palette.GetProof(fakeECCDContract.Hex(), hexutil.Encode(key), uint64ToHex(height-1))
+	require.NoError(t, err)
+	assert.Error(t, verifyProof(raw, prev.Root, fakeECCDContract, key, evt.Rawdata))
+
+	// tampered trie node breaks the merkle proof.
+	raw, err = env.palette.GetProof(fakeECCDContract.Hex(), hexutil.Encode(key), uint64ToHex(height))
+	require.NoError(t, err)
+	proof := new(pltProof)
+	require.NoError(t, json.Unmarshal(raw, proof))
+	node := pltcm.FromHex(proof.StorageProofs[0].Proof[0])
+	node[len(node)-1] ^= 0xff
+	proof.StorageProofs[0].Proof[0] = hexutil.Encode(node)
+	raw, err = json.Marshal(proof)
+	require.NoError(t, err)
+	assert.Error(t, verifyProof(raw, hdr.Root, fakeECCDContract, key, evt.Rawdata))
+}
+
+func TestFakePaletteInvalidProofNotCommitted(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	calls := 0
+	env.poly.ImportOuterTransferHook = func(_ *fake.ImportedTransfer) error {
+		calls++
+		return nil
+	}
+
+	height, evt := env.emitLockEvent(1)
+	env.palette.Mine(int(mgr.safeBlockDistance()) + 1)
+	refHeight := syncPalette(t, mgr)
+
+	// palette node returns a broken account proof.
+	crossTx, _ := serializeCrossTransfer(evt, height)
+	key, err := getMappingKey(crossTx.txIndex)
+	require.NoError(t, err)
+	proofHeight := uint64ToHex(refHeight - 1)
+	raw, err := env.palette.GetProof(fakeECCDContract.Hex(), hexutil.Encode(key), proofHeight)
+	require.NoError(t, err)
+	proof := new(pltProof)
+	require.NoError(t, json.Unmarshal(raw, proof))
+	node := pltcm.FromHex(proof.AccountProof[0])
+	node[len(node)-1] ^= 0xff
+	proof.AccountProof[0] = hexutil.Encode(node)
+	raw, err = json.Marshal(proof)
+	require.NoError(t, err)
+	env.palette.SetProof(fakeECCDContract.Hex(), hexutil.Encode(key), proofHeight, raw)
+
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(retryList))
+
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	assert.Equal(t, 0, calls)
+	state := mgr.retryState(retryList[0])
+	assert.Equal(t, uint32(1), state.attempts)
+	assert.Contains(t, state.lastErr, "invalid proof")
+}
@@ -0,0 +1,143 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	pltcm "github.com/ethereum/go-ethereum/common"
+	plttyp "github.com/ethereum/go-ethereum/core/types"
+	"github.com/palettechain/palette-relayer/log"
+)
+
+// detectReorg compare the parent hash of header with the checkpoint of its parent block, and return
+// the height of common ancestor of local checkpoints and palette node if they are mismatched.
+func (m *PaletteManager) detectReorg(hdr *plttyp.Header) (uint64, bool, error) {
+	height := hdr.Number.Uint64()
+	if height == 0 {
+		return 0, false, nil
+	}
+
+	checkpoint := m.db.GetPaletteBlockHash(height - 1)
+	if checkpoint == nil || bytes.Equal(checkpoint, hdr.ParentHash.Bytes()) {
+		return 0, false, nil
+	}
+
+	log.Warnf("PaletteManager detectReorg - parent hash of block %d is %s, but checkpoint of block %d is %s",
+		height, hdr.ParentHash.Hex(), height-1, pltcm.BytesToHash(checkpoint).Hex())
+
+	ancestor, err := m.findCommonAncestor(height - 1)
+	if err != nil {
+		return 0, false, err
+	}
+	return ancestor, true, nil
+}
+
+// findCommonAncestor walk back from `height` until the hash of palette node's block equals to the checkpoint.
+// if all of checkpoints mismatched, the height just before the oldest checkpoint is returned.
+func (m *PaletteManager) findCommonAncestor(height uint64) (uint64, error) {
+	for h := height; ; h-- {
+		checkpoint := m.db.GetPaletteBlockHash(h)
+		if checkpoint == nil {
+			log.Errorf("PaletteManager findCommonAncestor - no common ancestor found in checkpoints, "+
+				"rescan from block %d", h+1)
+			return h, nil
+		}
+
+		hdr, err := m.paletteClient.HeaderByNumber(context.Background(), uint64ToBig(h))
+		if err != nil {
+			return 0, fmt.Errorf("get header on height %d err: %s", h, err)
+		}
+		if bytes.Equal(hdr.Hash().Bytes(), checkpoint) || h == 0 {
+			return h, nil
+		}
+	}
+}
+
+// rollback drop checkpoints, validators and cross chain events of orphaned blocks after `ancestor`,
+// and rescan palette chain from the next block of ancestor.
+func (m *PaletteManager) rollback(ancestor uint64) {
+	next := ancestor + 1
+	if err := m.db.DeletePaletteBlockHashFrom(next); err != nil {
+		log.Errorf("PaletteManager rollback - delete checkpoints from %d err: %s", next, err)
+	}
+	if err := m.db.DeleteValSetFrom(next); err != nil {
+		log.Errorf("PaletteManager rollback - delete validators history from %d err: %s", next, err)
+	}
+	m.purgeOrphanedRetry(ancestor)
+	if err := m.db.UpdatePaletteHeight(ancestor); err != nil {
+		log.Errorf("PaletteManager rollback - update palette height err: %s", err)
+	}
+
+	if m.curHeader != nil && m.curHeader.height > ancestor {
+		m.curHeader = nil
+	}
+	pending := make([]*pltEpoch, 0, len(m.pendingHeaders))
+	for _, hdr := range m.pendingHeaders {
+		if hdr.height <= ancestor {
+			pending = append(pending, hdr)
+		}
+	}
+	m.pendingHeaders = pending
+	if m.inflight != nil && m.inflight.headers[len(m.inflight.headers)-1].height > ancestor {
+		log.Errorf("PaletteManager rollback - headers of in-flight poly tx %s are orphaned, common ancestor is %d",
+			m.inflight.txHash, ancestor)
+	}
+	if m.lastEpoch.height > ancestor {
+		log.Errorf("PaletteManager rollback - header of epoch %d which already synced to poly is orphaned, "+
+			"common ancestor is %d", m.lastEpoch.height, ancestor)
+	}
+
+	log.Warnf("PaletteManager rollback - palette chain reorganized, roll back from %d to common ancestor %d",
+		m.syncProgress.get(), ancestor)
+	m.depositProgress.lower(next)
+	m.syncProgress.set(next)
+}
+
+// purgeOrphanedRetry delete cross transfers in `retry` bucket which emitted after block `ancestor`.
+func (m *PaletteManager) purgeOrphanedRetry(ancestor uint64) {
+	var retryList [][]byte
+	for cursor := []byte(nil); ; {
+		entries, next, err := m.db.ListRetry(cursor, m.checkBatchSize())
+		if err != nil {
+			log.Errorf("PaletteManager purgeOrphanedRetry - m.db.ListRetry error: %s", err)
+			return
+		}
+		for _, entry := range entries {
+			retryList = append(retryList, entry.Key)
+		}
+		if cursor = next; cursor == nil {
+			break
+		}
+	}
+
+	for _, v := range retryList {
+		crossTx, err := deserializeCrossTransfer(v)
+		if err != nil || crossTx.height <= ancestor {
+			continue
+		}
+		if err := m.db.DeleteRetry(v); err != nil {
+			log.Errorf("PaletteManager purgeOrphanedRetry - m.db.DeleteRetry error: %s", err)
+			continue
+		}
+		m.deposits.drop(v)
+		log.Infof("PaletteManager purgeOrphanedRetry - drop tx %s of orphaned block %d",
+			txIdHex(crossTx.txId), crossTx.height)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/palettechain/palette-relayer/log"
+)
+
+const (
+	// defaultPendingTimeout is used if `PaletteConfig.PendingTimeout` is not configured.
+	defaultPendingTimeout = 2 * time.Minute
+
+	// defaultGasPriceBump is used if `PaletteConfig.GasPriceBump` is not configured, palette node
+	// rejects replacements whose gas price is bumped less than 10 percent.
+	defaultGasPriceBump = 10
+
+	// defaultMaxReplacements is used if `PaletteConfig.MaxReplacements` is not configured.
+	defaultMaxReplacements = 5
+
+	// defaultConfirmations is used if `PaletteConfig.Confirmations` is not configured, which means the
+	// transaction is final once it's mined.
+	defaultConfirmations = 1
+
+	// defaultConfirmTimeout is used if `PaletteConfig.ConfirmTimeout` is not configured.
+	defaultConfirmTimeout = 30 * time.Minute
+
+	// defaultTxPollInterval is used if `PaletteConfig.TxPollInterval` is not configured.
+	defaultTxPollInterval = 2 * time.Second
+)
+
+// replaceTransaction rebroadcast the pending transaction with the same nonce and bumped gas price,
+// and return nil if the gas price reaches the ceiling or the replacement is rejected.
+func (s *PaletteSender) replaceTransaction(polyTxHash string, tx *types.Transaction) *types.Transaction {
+	price, ok := s.bumpGasPrice(tx.GasPrice())
+	if !ok {
+		log.Errorf("PolyManager replaceTransaction - eth_transaction %s of poly_tx %s is pending, but gas price %s "+
+			"reaches the ceiling", tx.Hash().String(), polyTxHash, tx.GasPrice())
+		return nil
+	}
+
+	replacement := types.NewTransaction(tx.Nonce(), *tx.To(), tx.Value(), tx.Gas(), price, tx.Data())
+	signedTx, err := s.keyStore.SignTransaction(replacement, s.acc)
+	if err != nil {
+		log.Errorf("PolyManager replaceTransaction - sign replacement of eth_transaction %s error: %v",
+			tx.Hash().String(), err)
+		return nil
+	}
+	if err := s.paletteClient.SendTransaction(s.ctx, signedTx, bind.PrivateTxArgs{}); err != nil {
+		log.Warnf("PolyManager replaceTransaction - send replacement of eth_transaction %s error: %v",
+			tx.Hash().String(), err)
+		return nil
+	}
+
+	log.Warnf("PolyManager replaceTransaction - eth_transaction %s of poly_tx %s is pending longer than %s, "+
+		"replaced by %s with gas price %s, curNonce: %d", tx.Hash().String(), polyTxHash, s.pendingTimeout(),
+		signedTx.Hash().String(), price, tx.Nonce())
+	return signedTx
+}
+
+// bumpGasPrice raise gas price by `PaletteConfig.GasPriceBump` percent, and at least 1 wei. the bumped price
+// is capped by `PaletteConfig.MaxGasPrice`, and false is returned if the price already reaches it.
+func (s *PaletteSender) bumpGasPrice(price *big.Int) (*big.Int, bool) {
+	bumped := new(big.Int).Mul(price, big.NewInt(int64(100+s.gasPriceBump())))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(price) <= 0 {
+		bumped.Add(price, big.NewInt(1))
+	}
+
+	if s.config.PaletteConfig.MaxGasPrice > 0 {
+		max := new(big.Int).SetUint64(s.config.PaletteConfig.MaxGasPrice)
+		if price.Cmp(max) >= 0 {
+			return nil, false
+		}
+		if bumped.Cmp(max) > 0 {
+			bumped = max
+		}
+	}
+	return bumped, true
+}
+
+func (s *PaletteSender) pendingTimeout() time.Duration {
+	if s.config.PaletteConfig.PendingTimeout <= 0 {
+		return defaultPendingTimeout
+	}
+	return time.Duration(s.config.PaletteConfig.PendingTimeout) * time.Second
+}
+
+func (s *PaletteSender) gasPriceBump() int {
+	if s.config.PaletteConfig.GasPriceBump <= 0 {
+		return defaultGasPriceBump
+	}
+	return s.config.PaletteConfig.GasPriceBump
+}
+
+func (s *PaletteSender) maxReplacements() int {
+	if s.config.PaletteConfig.MaxReplacements <= 0 {
+		return defaultMaxReplacements
+	}
+	return s.config.PaletteConfig.MaxReplacements
+}
+
+// confirmations return the number of blocks including the one packed transaction, after which the
+// transaction is considered final.
+func (s *PaletteSender) confirmations() int {
+	if s.config.PaletteConfig.Confirmations <= 0 {
+		return defaultConfirmations
+	}
+	return s.config.PaletteConfig.Confirmations
+}
+
+// confirmTimeout return the max duration for waiting palette transaction confirmed, including replacements.
+func (s *PaletteSender) confirmTimeout() time.Duration {
+	if s.config.PaletteConfig.ConfirmTimeout <= 0 {
+		return defaultConfirmTimeout
+	}
+	return time.Duration(s.config.PaletteConfig.ConfirmTimeout) * time.Second
+}
+
+// txPollInterval return the interval of polling receipts of palette transactions, `PaletteConfig.TxPollInterval`
+// is in milliseconds.
+func (s *PaletteSender) txPollInterval() time.Duration {
+	if s.config.PaletteConfig.TxPollInterval <= 0 {
+		return defaultTxPollInterval
+	}
+	return time.Duration(s.config.PaletteConfig.TxPollInterval) * time.Millisecond
+}
@@ -0,0 +1,235 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/palettechain/palette-relayer/db"
+	"github.com/palettechain/palette-relayer/log"
+	polycm "github.com/polynetwork/poly/common"
+)
+
+const (
+	// defaultRetryInterval is used if `PaletteConfig.RetryInterval` is not configured.
+	defaultRetryInterval = 10 * time.Second
+
+	// defaultMaxRetryInterval is used if `PaletteConfig.MaxRetryInterval` is not configured.
+	defaultMaxRetryInterval = time.Hour
+
+	// defaultMaxRetryAttempts is used if `PaletteConfig.MaxRetryAttempts` is not configured.
+	defaultMaxRetryAttempts = 20
+
+	// backlogLogInterval is the min interval between two backlog reports.
+	backlogLogInterval = time.Minute
+)
+
+// DeadLetter is the cross transfer which exceeds max retry attempts, it is kept in `dead letter`
+// bucket for operators to inspect and requeue.
+type DeadLetter struct {
+	Key      string
+	TxId     string
+	Height   uint64
+	Attempts uint32
+	LastErr  string
+}
+
+// retryState return the retry state of cross transfer, and an empty state if it never failed.
+func (m *PaletteManager) retryState(v []byte) *RetryState {
+	state := new(RetryState)
+	raw := m.db.GetRetryState(v)
+	if raw == nil {
+		return state
+	}
+	if err := state.Deserialization(polycm.NewZeroCopySource(raw)); err != nil {
+		log.Errorf("PaletteManager retryState - deserialize retry state err: %s", err)
+		return new(RetryState)
+	}
+	return state
+}
+
+// retryFailed record the failed attempt of cross transfer and delay the next attempt exponentially,
+// the cross transfer is moved into `dead letter` bucket if it exceeds max retry attempts.
+func (m *PaletteManager) retryFailed(v []byte, cause error) {
+	state := m.retryState(v)
+	state.attempts++
+	state.lastErr = cause.Error()
+	state.nextTime = time.Now().Add(m.retryBackoff(state.attempts)).Unix()
+
+	sink := polycm.NewZeroCopySink(nil)
+	state.Serialization(sink)
+
+	if state.attempts >= m.maxRetryAttempts() {
+		if err := m.db.MoveRetryToDeadLetter(v, sink.Bytes()); err != nil {
+			log.Errorf("PaletteManager retryFailed - m.db.MoveRetryToDeadLetter error: %s", err)
+			return
+		}
+		log.Errorf("PaletteManager retryFailed - cross transfer %s moved to dead letter after %d attempts, "+
+			"last err: %s", hex.EncodeToString(v), state.attempts, state.lastErr)
+		return
+	}
+
+	if err := m.db.UpdateRetry(v, sink.Bytes()); err != nil {
+		log.Errorf("PaletteManager retryFailed - m.db.UpdateRetry error: %s", err)
+		return
+	}
+	m.requeueDeposit(v, state.nextTime)
+}
+
+// retryBackoff return base interval * 2^(attempts-1), and it is capped by the max interval.
+func (m *PaletteManager) retryBackoff(attempts uint32) time.Duration {
+	base, max := m.retryInterval(), m.maxRetryInterval()
+	backoff := base
+	for i := uint32(1); i < attempts && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+func (m *PaletteManager) retryInterval() time.Duration {
+	if m.config.PaletteConfig.RetryInterval <= 0 {
+		return defaultRetryInterval
+	}
+	return time.Duration(m.config.PaletteConfig.RetryInterval) * time.Second
+}
+
+func (m *PaletteManager) maxRetryInterval() time.Duration {
+	if m.config.PaletteConfig.MaxRetryInterval <= 0 {
+		return defaultMaxRetryInterval
+	}
+	return time.Duration(m.config.PaletteConfig.MaxRetryInterval) * time.Second
+}
+
+func (m *PaletteManager) maxRetryAttempts() uint32 {
+	if m.config.PaletteConfig.MaxRetryAttempts <= 0 {
+		return defaultMaxRetryAttempts
+	}
+	return uint32(m.config.PaletteConfig.MaxRetryAttempts)
+}
+
+// Backlog is the number of cross transfers waiting in each bucket, and the number of palette transactions
+// which can never succeed.
+type Backlog struct {
+	Check             int
+	Retry             int
+	DeadLetter        int
+	PaletteDeadLetter int
+}
+
+// GetBacklog count poly transactions in `check` bucket, cross transfers in `retry` and `dead letter` buckets,
+// and palette transactions in `palette dead letter` bucket.
+func GetBacklog(boltDB *db.BoltDB) (*Backlog, error) {
+	var (
+		backlog = new(Backlog)
+		err     error
+	)
+	if backlog.Check, err = boltDB.CountCheck(); err != nil {
+		return nil, err
+	}
+	if backlog.Retry, err = boltDB.CountRetry(); err != nil {
+		return nil, err
+	}
+	if backlog.DeadLetter, err = boltDB.CountDeadLetter(); err != nil {
+		return nil, err
+	}
+	if backlog.PaletteDeadLetter, err = boltDB.CountPaletteDeadLetter(); err != nil {
+		return nil, err
+	}
+	return backlog, nil
+}
+
+// logBacklog report the size of backlog and the number of timed out poly transactions if any of them is not zero,
+// at most once every `backlogLogInterval`.
+func (m *PaletteManager) logBacklog() {
+	if time.Since(m.backlogLogTime) < backlogLogInterval {
+		return
+	}
+	backlog, err := GetBacklog(m.db)
+	if err != nil {
+		log.Errorf("PaletteManager logBacklog - GetBacklog error: %s", err)
+		return
+	}
+	timeouts := atomic.LoadUint64(&m.checkTimeouts)
+	if *backlog == (Backlog{}) && timeouts == 0 {
+		return
+	}
+	m.backlogLogTime = time.Now()
+	log.Infof("PaletteManager logBacklog - %d poly txs to check, %d cross transfers to retry, %d dead letters, "+
+		"%d palette dead letters, %d poly txs timed out since started", backlog.Check, backlog.Retry,
+		backlog.DeadLetter, backlog.PaletteDeadLetter, timeouts)
+}
+
+// ListDeadLetters return all of cross transfers in `dead letter` bucket in ascending order of block height.
+func ListDeadLetters(boltDB *db.BoltDB) ([]*DeadLetter, error) {
+	list := make([]*DeadLetter, 0)
+	var decodeErr error
+	err := boltDB.ForEachDeadLetter(func(k, v []byte) {
+		if decodeErr != nil {
+			return
+		}
+		key := hex.EncodeToString(k)
+		crossTx, err := deserializeCrossTransfer(k)
+		if err != nil {
+			decodeErr = fmt.Errorf("deserialize dead letter %s err: %s", key, err)
+			return
+		}
+		state := new(RetryState)
+		if err := state.Deserialization(polycm.NewZeroCopySource(v)); err != nil {
+			decodeErr = fmt.Errorf("deserialize retry state of dead letter %s err: %s", key, err)
+			return
+		}
+
+		list = append(list, &DeadLetter{
+			Key:      key,
+			TxId:     txIdHex(crossTx.txId),
+			Height:   crossTx.height,
+			Attempts: state.attempts,
+			LastErr:  state.lastErr,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Height == list[j].Height {
+			return list[i].Key < list[j].Key
+		}
+		return list[i].Height < list[j].Height
+	})
+	return list, nil
+}
+
+// RequeueDeadLetter move the dead letter identified by hex `key` back to `retry` bucket. it's called while
+// relayer is stopped, and the cross transfer is picked up by the deposit queue at the next startup.
+func RequeueDeadLetter(boltDB *db.BoltDB, key string) error {
+	raw, err := hex.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("invalid dead letter key %s, err: %s", key, err)
+	}
+	return boltDB.RequeueDeadLetter(raw)
+}
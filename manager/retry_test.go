@@ -0,0 +1,234 @@
+package manager
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/palettechain/palette-relayer/config"
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/polynetwork/eth-contracts/go_abi/eccm_abi"
+	polycm "github.com/polynetwork/poly/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	mgr := &PaletteManager{config: &config.ServiceConfig{PaletteConfig: &config.PaletteConfig{
+		RetryInterval:    10,
+		MaxRetryInterval: 60,
+	}}}
+
+	expect := []time.Duration{10, 20, 40, 60, 60, 60}
+	for i, v := range expect {
+		assert.Equal(t, v*time.Second, mgr.retryBackoff(uint32(i+1)))
+	}
+}
+
+// expireBackoff make the failed cross transfer eligible for retry at once.
+func expireBackoff(t *testing.T, mgr *PaletteManager, v []byte) {
+	state := mgr.retryState(v)
+	state.nextTime = 0
+	sink := polycm.NewZeroCopySink(nil)
+	state.Serialization(sink)
+	require.NoError(t, mgr.db.UpdateRetry(v, sink.Bytes()))
+	mgr.requeueDeposit(v, 0)
+}
+
+func TestFakePaletteRetryDeadLetter(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.MaxRetryAttempts = 3
+	mgr := env.paletteManager(t)
+
+	calls := 0
+	env.poly.ImportOuterTransferHook = func(_ *fake.ImportedTransfer) error {
+		calls++
+		return fmt.Errorf("verify proof failed")
+	}
+
+	env.emitLockEvent(1)
+	env.palette.Mine(int(mgr.safeBlockDistance()) + 1)
+	refHeight := syncPalette(t, mgr)
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(retryList))
+	v := retryList[0]
+
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	state := mgr.retryState(v)
+	assert.Equal(t, uint32(1), state.attempts)
+	assert.Equal(t, "verify proof failed", state.lastErr)
+	assert.True(t, state.nextTime > time.Now().Unix())
+
+	// cross transfer in backoff is not retried.
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	assert.Equal(t, 1, calls)
+
+	// fetching the same event again keeps its retry state.
+	require.NoError(t, env.db.PutRetry(v))
+	assert.Equal(t, uint32(1), mgr.retryState(v).attempts)
+
+	for i := 0; i < 2; i++ {
+		expireBackoff(t, mgr, v)
+		require.NoError(t, mgr.handleDepositEvents(refHeight))
+	}
+	assert.Equal(t, 3, calls)
+	retryList, err = env.db.GetAllRetry()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(retryList))
+
+	deadList, err := ListDeadLetters(env.db)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(deadList))
+	assert.Equal(t, uint32(3), deadList[0].Attempts)
+	assert.Equal(t, "verify proof failed", deadList[0].LastErr)
+
+	// requeued dead letter is retried with a fresh state.
+	env.poly.ImportOuterTransferHook = nil
+	require.NoError(t, RequeueDeadLetter(env.db, deadList[0].Key))
+	assert.Equal(t, uint32(0), mgr.retryState(v).attempts)
+	// dead letters are requeued while relayer stopped, and loaded on restart.
+	require.NoError(t, mgr.loadDeposits())
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	assert.Equal(t, 1, len(env.poly.ImportedTransfers()))
+
+	deadList, err = ListDeadLetters(env.db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(deadList))
+	assert.Error(t, RequeueDeadLetter(env.db, hex.EncodeToString(v)))
+}
+
+func TestListDeadLettersUnlimited(t *testing.T) {
+	env := newFakeEnv(t)
+
+	// more dead letters than the limit of `GetAllRetry` are listed.
+	const num = 1001
+	state := polycm.NewZeroCopySink(nil)
+	(&RetryState{attempts: 3, lastErr: "failed"}).Serialization(state)
+	for i := 0; i < num; i++ {
+		txId := []byte{byte(i >> 8), byte(i)}
+		_, sink := serializeCrossTransfer(&eccm_abi.EthCrossChainManagerCrossChainEvent{TxId: txId}, uint64(i))
+		require.NoError(t, env.db.MoveRetryToDeadLetter(sink.Bytes(), state.Bytes()))
+	}
+
+	deadList, err := ListDeadLetters(env.db)
+	require.NoError(t, err)
+	require.Equal(t, num, len(deadList))
+	assert.Equal(t, uint64(num-1), deadList[num-1].Height)
+}
+
+func TestBacklogCounters(t *testing.T) {
+	env := newFakeEnv(t)
+	_, sink := serializeCrossTransfer(&eccm_abi.EthCrossChainManagerCrossChainEvent{TxId: []byte{1}}, 1)
+	key := sink.Bytes()
+	txHash := hex.EncodeToString(make([]byte, 32))
+
+	// counters are kept in memory, and updated only if the key is added or removed.
+	require.NoError(t, env.db.PutCheck(txHash, key))
+	require.NoError(t, env.db.PutCheck(txHash, key))
+	require.NoError(t, env.db.PutRetry(key))
+	require.NoError(t, env.db.PutRetry(key))
+	require.NoError(t, env.db.UpdateRetry(key, []byte{1}))
+	backlog, err := GetBacklog(env.db)
+	require.NoError(t, err)
+	assert.Equal(t, &Backlog{Check: 1, Retry: 1}, backlog)
+
+	require.NoError(t, env.db.MoveRetryToDeadLetter(key, []byte{1}))
+	backlog, err = GetBacklog(env.db)
+	require.NoError(t, err)
+	assert.Equal(t, &Backlog{Check: 1, DeadLetter: 1}, backlog)
+
+	require.NoError(t, env.db.RequeueDeadLetter(key))
+	assert.Error(t, env.db.RequeueDeadLetter(key))
+	require.NoError(t, env.db.DeleteCheck(txHash))
+	require.NoError(t, env.db.DeleteCheck(txHash))
+	backlog, err = GetBacklog(env.db)
+	require.NoError(t, err)
+	assert.Equal(t, &Backlog{Retry: 1}, backlog)
+
+	require.NoError(t, env.db.DeleteRetry(key))
+	require.NoError(t, env.db.DeleteRetry(key))
+	backlog, err = GetBacklog(env.db)
+	require.NoError(t, err)
+	assert.Equal(t, &Backlog{}, backlog)
+}
+
+func TestFakePaletteDepositErrorCategory(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	env.emitLockEvent(1)
+	env.palette.Mine(int(mgr.safeBlockDistance()) + 1)
+	refHeight := syncPalette(t, mgr)
+	retryList, err := env.db.GetAllRetry()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(retryList))
+	v := retryList[0]
+
+	// transient failures are not counted as attempts, and they are retried after retry interval
+	// rather than on every height.
+	calls := 0
+	env.poly.ImportOuterTransferHook = func(_ *fake.ImportedTransfer) error {
+		calls++
+		return fmt.Errorf("dial tcp: connection refused")
+	}
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	assert.Equal(t, uint32(0), mgr.retryState(v).attempts)
+	require.NoError(t, mgr.handleDepositEvents(refHeight+1))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, uint32(0), mgr.retryState(v).attempts)
+
+	// cross transfer already done on poly chain is deleted.
+	env.poly.ImportOuterTransferHook = func(_ *fake.ImportedTransfer) error {
+		return fmt.Errorf("[ImportExTransfer] tx already done")
+	}
+	mgr.requeueDeposit(v, 0)
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	retryList, err = env.db.GetAllRetry()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(retryList))
+}
+
+func TestFakePaletteCheckRounds(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.CheckBatchSize = 2
+	mgr := env.paletteManager(t)
+
+	const num = 5
+	for i := 0; i < num; i++ {
+		env.emitLockEvent(byte(i + 1))
+	}
+	env.palette.Mine(int(mgr.safeBlockDistance()) + 1)
+	refHeight := syncPalette(t, mgr)
+	require.NoError(t, mgr.handleDepositEvents(refHeight))
+	require.Equal(t, num, len(env.poly.ImportedTransfers()))
+	_, sink := serializeCrossTransfer(&eccm_abi.EthCrossChainManagerCrossChainEvent{TxId: []byte{num + 1}}, 1)
+
+	// poly txs which are never confirmed are placed before the others, and they do not starve the others.
+	pending := []string{hex.EncodeToString(make([]byte, 32)), hex.EncodeToString(append(make([]byte, 31), 1))}
+	for _, txHash := range pending {
+		require.NoError(t, mgr.putCheck(txHash, sink.Bytes()))
+	}
+
+	backlog, err := GetBacklog(env.db)
+	require.NoError(t, err)
+	assert.Equal(t, &Backlog{Check: num + len(pending)}, backlog)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, mgr.checkLockEvents())
+	}
+	backlog, err = GetBacklog(env.db)
+	require.NoError(t, err)
+	assert.Equal(t, len(pending), backlog.Check)
+
+	entries, next, err := env.db.ListCheck(nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, pending[0], hex.EncodeToString(entries[0].Key))
+	entries, next, err = env.db.ListCheck(next, 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, pending[1], hex.EncodeToString(entries[0].Key))
+	assert.Nil(t, next)
+}
@@ -0,0 +1,226 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/palettechain/palette-relayer/log"
+)
+
+const (
+	// SenderRandom pick a random sender, it's the default strategy.
+	SenderRandom = "random"
+
+	// SenderRoundRobin pick senders in turn.
+	SenderRoundRobin = "round-robin"
+
+	// SenderLeastPending pick the sender with the least queued and in-flight transactions.
+	SenderLeastPending = "least-pending"
+
+	// SenderBalanceWeighted pick a random sender with probability in proportion to its balance.
+	SenderBalanceWeighted = "balance-weighted"
+)
+
+const (
+	// defaultMaxSendFailures is used if `PaletteConfig.MaxSendFailures` is not configured.
+	defaultMaxSendFailures = 3
+
+	// defaultUnhealthyCooldown is used if `PaletteConfig.UnhealthyCooldown` is not configured.
+	defaultUnhealthyCooldown = 5 * time.Minute
+)
+
+// senderCandidate is the available sender and its balance, the balance is nil if it's not needed.
+type senderCandidate struct {
+	sender  *PaletteSender
+	balance *big.Int
+}
+
+// senderSelector pick one of available senders for the next palette transaction.
+type senderSelector interface {
+	name() string
+	needBalance() bool
+	pick(candidates []*senderCandidate) *PaletteSender
+}
+
+// newSenderSelector create the sender selector of strategy `PaletteConfig.SenderStrategy`.
+func newSenderSelector(strategy string) (senderSelector, error) {
+	switch strategy {
+	case "", SenderRandom:
+		return randomSelector{}, nil
+	case SenderRoundRobin:
+		return new(roundRobinSelector), nil
+	case SenderLeastPending:
+		return leastPendingSelector{}, nil
+	case SenderBalanceWeighted:
+		return balanceWeightedSelector{}, nil
+	default:
+		return nil, fmt.Errorf("unknown sender strategy %s", strategy)
+	}
+}
+
+type randomSelector struct{}
+
+func (randomSelector) name() string      { return SenderRandom }
+func (randomSelector) needBalance() bool { return false }
+
+func (randomSelector) pick(candidates []*senderCandidate) *PaletteSender {
+	return candidates[rand.Intn(len(candidates))].sender
+}
+
+// roundRobinSelector pick the next sender of the last picked one in order of accounts.
+type roundRobinSelector struct {
+	next uint64
+}
+
+func (*roundRobinSelector) name() string      { return SenderRoundRobin }
+func (*roundRobinSelector) needBalance() bool { return false }
+
+func (r *roundRobinSelector) pick(candidates []*senderCandidate) *PaletteSender {
+	next := atomic.AddUint64(&r.next, 1) - 1
+	return candidates[next%uint64(len(candidates))].sender
+}
+
+type leastPendingSelector struct{}
+
+func (leastPendingSelector) name() string      { return SenderLeastPending }
+func (leastPendingSelector) needBalance() bool { return false }
+
+func (leastPendingSelector) pick(candidates []*senderCandidate) *PaletteSender {
+	picked := candidates[0].sender
+	for _, c := range candidates[1:] {
+		if c.sender.pendingTxs() < picked.pendingTxs() {
+			picked = c.sender
+		}
+	}
+	return picked
+}
+
+type balanceWeightedSelector struct{}
+
+func (balanceWeightedSelector) name() string      { return SenderBalanceWeighted }
+func (balanceWeightedSelector) needBalance() bool { return true }
+
+func (balanceWeightedSelector) pick(candidates []*senderCandidate) *PaletteSender {
+	total := new(big.Int)
+	for _, c := range candidates {
+		total.Add(total, c.balance)
+	}
+	if total.Sign() == 0 {
+		return candidates[rand.Intn(len(candidates))].sender
+	}
+
+	point := new(big.Int).Rand(rand.New(rand.NewSource(rand.Int63())), total)
+	for _, c := range candidates {
+		if point.Cmp(c.balance) < 0 {
+			return c.sender
+		}
+		point.Sub(point, c.balance)
+	}
+	return candidates[len(candidates)-1].sender
+}
+
+// selectSender pick a sender by the strategy from healthy senders whose balance is not lower than
+// `PaletteConfig.MinSenderBalance`, and nil is returned if none of them is available. balances polled
+// by balance monitor are preferred, and palette node is queried if they are out of date.
+func (m *PolyManager) selectSender() *PaletteSender {
+	floor := new(big.Int).SetUint64(m.config.PaletteConfig.MinSenderBalance)
+	candidates := make([]*senderCandidate, 0, len(m.senders))
+	for _, s := range m.senders {
+		if !s.healthy() {
+			debug("PolyManager selectSender - skip unhealthy sender %s", s.acc.Address.Hex())
+			continue
+		}
+
+		c := &senderCandidate{sender: s}
+		if floor.Sign() > 0 || m.selector.needBalance() {
+			balance := s.polledBalance(2 * m.balanceCheckInterval())
+			if balance == nil {
+				var err error
+				if balance, err = m.paletteCli.BalanceAt(context.Background(), s.acc.Address, nil); err != nil {
+					log.Errorf("PolyManager selectSender - get balance of sender %s error: %s",
+						s.acc.Address.Hex(), err)
+					continue
+				}
+			}
+			if balance.Cmp(floor) < 0 {
+				log.Warnf("PolyManager selectSender - skip sender %s, balance %s is lower than %s",
+					s.acc.Address.Hex(), balance, floor)
+				continue
+			}
+			c.balance = balance
+		}
+		candidates = append(candidates, c)
+	}
+
+	if len(candidates) == 0 {
+		log.Errorf("PolyManager selectSender - none of %d senders is available", len(m.senders))
+		return nil
+	}
+	sender := m.selector.pick(candidates)
+	log.Infof("PolyManager selectSender - %s strategy picked sender %s from %d senders, pending txs: %d",
+		m.selector.name(), sender.acc.Address.Hex(), len(candidates), sender.pendingTxs())
+	return sender
+}
+
+// pendingTxs return the number of queued and in-flight transactions of sender.
+func (s *PaletteSender) pendingTxs() int64 {
+	return atomic.LoadInt64(&s.pending)
+}
+
+// healthy return false if the sender failed `PaletteConfig.MaxSendFailures` times in a row, and it
+// becomes healthy again after `PaletteConfig.UnhealthyCooldown`.
+func (s *PaletteSender) healthy() bool {
+	return time.Now().Unix() >= atomic.LoadInt64(&s.unhealthyUntil)
+}
+
+// recordSend count the consecutive failures of sending transactions, and mark the sender unhealthy
+// if it fails too many times.
+func (s *PaletteSender) recordSend(err error) {
+	if err == nil {
+		atomic.StoreInt32(&s.failures, 0)
+		return
+	}
+
+	failures := atomic.AddInt32(&s.failures, 1)
+	if int(failures) < s.maxSendFailures() {
+		return
+	}
+	atomic.StoreInt32(&s.failures, 0)
+	atomic.StoreInt64(&s.unhealthyUntil, time.Now().Add(s.unhealthyCooldown()).Unix())
+	log.Errorf("PolyManager - sender %s failed %d times in a row, mark it unhealthy for %s, last err: %v",
+		s.acc.Address.Hex(), failures, s.unhealthyCooldown(), err)
+}
+
+func (s *PaletteSender) maxSendFailures() int {
+	if s.config.PaletteConfig.MaxSendFailures <= 0 {
+		return defaultMaxSendFailures
+	}
+	return s.config.PaletteConfig.MaxSendFailures
+}
+
+func (s *PaletteSender) unhealthyCooldown() time.Duration {
+	if s.config.PaletteConfig.UnhealthyCooldown <= 0 {
+		return defaultUnhealthyCooldown
+	}
+	return time.Duration(s.config.PaletteConfig.UnhealthyCooldown) * time.Second
+}
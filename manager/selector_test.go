@@ -0,0 +1,86 @@
+package manager
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/palettechain/palette-relayer/config"
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderSelector(t *testing.T) {
+	senders := []*PaletteSender{{pending: 3}, {pending: 1}, {pending: 2}}
+	candidates := make([]*senderCandidate, len(senders))
+	for i, s := range senders {
+		candidates[i] = &senderCandidate{sender: s, balance: big.NewInt(0)}
+	}
+
+	rr, err := newSenderSelector(SenderRoundRobin)
+	require.NoError(t, err)
+	for i := 0; i < 2*len(senders); i++ {
+		assert.Equal(t, senders[i%len(senders)], rr.pick(candidates))
+	}
+
+	lp, err := newSenderSelector(SenderLeastPending)
+	require.NoError(t, err)
+	assert.Equal(t, senders[1], lp.pick(candidates))
+
+	bw, err := newSenderSelector(SenderBalanceWeighted)
+	require.NoError(t, err)
+	assert.True(t, bw.needBalance())
+	candidates[2].balance = big.NewInt(100)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, senders[2], bw.pick(candidates))
+	}
+
+	random, err := newSenderSelector("")
+	require.NoError(t, err)
+	assert.Equal(t, SenderRandom, random.name())
+	assert.Contains(t, senders, random.pick(candidates))
+
+	_, err = newSenderSelector("cheapest")
+	assert.Error(t, err)
+}
+
+func TestFakePolyManagerSelectSender(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.SenderStrategy = SenderRoundRobin
+	env.cfg.PaletteConfig.MinSenderBalance = 10
+	env.cfg.PaletteConfig.MaxSendFailures = 2
+	signer := fake.NewSigner(fakeSideChainID, fake.Key(1), fake.Key(2), fake.Key(3))
+	mgr, err := newPolyManager(env.cfg, 0, env.poly, env.palette, env.palette, signer, signer.Accounts(), env.db)
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	for i, balance := range []int64{5, 100, 50} {
+		env.palette.SetBalance(mgr.senders[i].acc.Address, big.NewInt(balance))
+	}
+
+	// sender whose balance is lower than the floor is skipped.
+	picked := make(map[*PaletteSender]int)
+	for i := 0; i < 4; i++ {
+		picked[mgr.selectSender()]++
+	}
+	assert.Equal(t, map[*PaletteSender]int{mgr.senders[1]: 2, mgr.senders[2]: 2}, picked)
+
+	// sender failed too many times is skipped until it recovers.
+	mgr.senders[1].recordSend(fmt.Errorf("send transaction error"))
+	assert.True(t, mgr.senders[1].healthy())
+	mgr.senders[1].recordSend(fmt.Errorf("send transaction error"))
+	assert.False(t, mgr.senders[1].healthy())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, mgr.senders[2], mgr.selectSender())
+	}
+
+	env.palette.SetBalance(mgr.senders[2].acc.Address, big.NewInt(0))
+	assert.Nil(t, mgr.selectSender())
+	mgr.senders[1].unhealthyUntil = 0
+	assert.Equal(t, mgr.senders[1], mgr.selectSender())
+
+	_, err = newPolyManager(&config.ServiceConfig{PaletteConfig: &config.PaletteConfig{SenderStrategy: "cheapest"}},
+		0, env.poly, env.palette, env.palette, signer, signer.Accounts(), env.db)
+	assert.Error(t, err)
+}
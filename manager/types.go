@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math/big"
 
@@ -9,9 +10,8 @@ import (
 )
 
 var (
-	paletteGasLimit   uint64 = 210000
-	paletteTxGasPrice        = big.NewInt(0)
-	paletteTxValue           = big.NewInt(0)
+	paletteGasLimit uint64 = 210000
+	paletteTxValue         = big.NewInt(0)
 )
 
 type CrossTransfer struct {
@@ -59,10 +59,244 @@ func (t *CrossTransfer) Deserialization(source *common.ZeroCopySource) error {
 	return nil
 }
 
+// RetryState is the retry record of cross transfer in `retry` and `dead letter` buckets.
+type RetryState struct {
+	attempts uint32
+	lastErr  string
+	nextTime int64
+}
+
+func (r *RetryState) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint32(r.attempts)
+	sink.WriteString(r.lastErr)
+	sink.WriteInt64(r.nextTime)
+}
+
+func (r *RetryState) Deserialization(source *common.ZeroCopySource) error {
+	attempts, eof := source.NextUint32()
+	if eof {
+		return fmt.Errorf("Waiting deserialize attempts error")
+	}
+	lastErr, eof := source.NextString()
+	if eof {
+		return fmt.Errorf("Waiting deserialize lastErr error")
+	}
+	nextTime, eof := source.NextInt64()
+	if eof {
+		return fmt.Errorf("Waiting deserialize nextTime error")
+	}
+	r.attempts = attempts
+	r.lastErr = lastErr
+	r.nextTime = nextTime
+	return nil
+}
+
+// CheckRecord is the cross transfer in `check` bucket, whose proof is submitted to poly chain at `submitTime`.
+type CheckRecord struct {
+	submitTime int64
+	crossTx    []byte
+}
+
+func (r *CheckRecord) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteInt64(r.submitTime)
+	sink.WriteVarBytes(r.crossTx)
+}
+
+func (r *CheckRecord) Deserialization(source *common.ZeroCopySource) error {
+	submitTime, eof := source.NextInt64()
+	if eof {
+		return fmt.Errorf("Waiting deserialize submitTime error")
+	}
+	crossTx, eof := source.NextVarBytes()
+	if eof {
+		return fmt.Errorf("Waiting deserialize crossTx error")
+	}
+	r.submitTime = submitTime
+	r.crossTx = crossTx
+	return nil
+}
+
+// HeaderCommit is a batch of palette epoch headers which is submitted to poly chain in transaction
+// `txHash` and waiting for confirmation.
+type HeaderCommit struct {
+	txHash     string
+	submitTime int64
+	headers    []*pltEpoch
+}
+
+func (c *HeaderCommit) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteString(c.txHash)
+	sink.WriteInt64(c.submitTime)
+	sink.WriteVarUint(uint64(len(c.headers)))
+	for _, hdr := range c.headers {
+		sink.WriteUint64(hdr.height)
+		sink.WriteVarBytes(hdr.raw)
+		sink.WriteVarUint(uint64(len(hdr.valset)))
+		for _, addr := range hdr.valset {
+			sink.WriteVarBytes(addr.Bytes())
+		}
+	}
+}
+
+func (c *HeaderCommit) Deserialization(source *common.ZeroCopySource) error {
+	txHash, eof := source.NextString()
+	if eof {
+		return fmt.Errorf("Waiting deserialize txHash error")
+	}
+	submitTime, eof := source.NextInt64()
+	if eof {
+		return fmt.Errorf("Waiting deserialize submitTime error")
+	}
+	num, eof := source.NextVarUint()
+	if eof {
+		return fmt.Errorf("Waiting deserialize headers number error")
+	}
+	headers := make([]*pltEpoch, 0, num)
+	for i := uint64(0); i < num; i++ {
+		height, eof := source.NextUint64()
+		if eof {
+			return fmt.Errorf("Waiting deserialize header height error")
+		}
+		raw, eof := source.NextVarBytes()
+		if eof {
+			return fmt.Errorf("Waiting deserialize header raw error")
+		}
+		size, eof := source.NextVarUint()
+		if eof {
+			return fmt.Errorf("Waiting deserialize valset size error")
+		}
+		valset := make([]ethcommon.Address, 0, size)
+		for j := uint64(0); j < size; j++ {
+			addr, eof := source.NextVarBytes()
+			if eof {
+				return fmt.Errorf("Waiting deserialize validator error")
+			}
+			valset = append(valset, ethcommon.BytesToAddress(addr))
+		}
+		headers = append(headers, &pltEpoch{height: height, raw: raw, valset: valset})
+	}
+	c.txHash = txHash
+	c.submitTime = submitTime
+	c.headers = headers
+	return nil
+}
+
+// ValSetRecord is the palette validators which changed in epoch block at `Height`, validators are
+// sorted by address and `raw` is the json encoded epoch header.
+type ValSetRecord struct {
+	Height     uint64
+	Hash       ethcommon.Hash
+	Validators []ethcommon.Address
+	raw        []byte
+}
+
+func (r *ValSetRecord) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(r.Height)
+	sink.WriteVarBytes(r.Hash.Bytes())
+	sink.WriteVarUint(uint64(len(r.Validators)))
+	for _, addr := range r.Validators {
+		sink.WriteVarBytes(addr.Bytes())
+	}
+	sink.WriteVarBytes(r.raw)
+}
+
+func (r *ValSetRecord) Deserialization(source *common.ZeroCopySource) error {
+	height, eof := source.NextUint64()
+	if eof {
+		return fmt.Errorf("Waiting deserialize height error")
+	}
+	hash, eof := source.NextVarBytes()
+	if eof {
+		return fmt.Errorf("Waiting deserialize hash error")
+	}
+	size, eof := source.NextVarUint()
+	if eof {
+		return fmt.Errorf("Waiting deserialize valset size error")
+	}
+	valset := make([]ethcommon.Address, 0, size)
+	for i := uint64(0); i < size; i++ {
+		addr, eof := source.NextVarBytes()
+		if eof {
+			return fmt.Errorf("Waiting deserialize validator error")
+		}
+		valset = append(valset, ethcommon.BytesToAddress(addr))
+	}
+	raw, eof := source.NextVarBytes()
+	if eof {
+		return fmt.Errorf("Waiting deserialize header raw error")
+	}
+	r.Height = height
+	r.Hash = ethcommon.BytesToHash(hash)
+	r.Validators = valset
+	r.raw = raw
+	return nil
+}
+
+// PaletteTxInfo is the palette transaction relaying poly transaction `polyTxHash`, which is persisted in
+// `palette tx` bucket until it's sent.
 type PaletteTxInfo struct {
 	txData       []byte
 	gasLimit     uint64
-	gasPrice     *big.Int
 	contractAddr ethcommon.Address
 	polyTxHash   string
+	polyHeight   uint32
+	fromChainID  uint64
+	fromTx       [32]byte
+}
+
+// key is ordered by poly height, so that transactions are replayed in the order of poly blocks.
+func (i *PaletteTxInfo) key() []byte {
+	key := make([]byte, 12, 12+len(i.fromTx))
+	binary.BigEndian.PutUint32(key, i.polyHeight)
+	binary.BigEndian.PutUint64(key[4:], i.fromChainID)
+	return append(key, i.fromTx[:]...)
+}
+
+func (i *PaletteTxInfo) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarBytes(i.txData)
+	sink.WriteUint64(i.gasLimit)
+	sink.WriteVarBytes(i.contractAddr.Bytes())
+	sink.WriteString(i.polyTxHash)
+	sink.WriteUint32(i.polyHeight)
+	sink.WriteUint64(i.fromChainID)
+	sink.WriteVarBytes(i.fromTx[:])
+}
+
+func (i *PaletteTxInfo) Deserialization(source *common.ZeroCopySource) error {
+	txData, eof := source.NextVarBytes()
+	if eof {
+		return fmt.Errorf("Waiting deserialize txData error")
+	}
+	gasLimit, eof := source.NextUint64()
+	if eof {
+		return fmt.Errorf("Waiting deserialize gasLimit error")
+	}
+	contractAddr, eof := source.NextVarBytes()
+	if eof {
+		return fmt.Errorf("Waiting deserialize contractAddr error")
+	}
+	polyTxHash, eof := source.NextString()
+	if eof {
+		return fmt.Errorf("Waiting deserialize polyTxHash error")
+	}
+	polyHeight, eof := source.NextUint32()
+	if eof {
+		return fmt.Errorf("Waiting deserialize polyHeight error")
+	}
+	fromChainID, eof := source.NextUint64()
+	if eof {
+		return fmt.Errorf("Waiting deserialize fromChainID error")
+	}
+	fromTx, eof := source.NextVarBytes()
+	if eof || len(fromTx) != 32 {
+		return fmt.Errorf("Waiting deserialize fromTx error")
+	}
+	i.txData = txData
+	i.gasLimit = gasLimit
+	i.contractAddr = ethcommon.BytesToAddress(contractAddr)
+	i.polyTxHash = polyTxHash
+	i.polyHeight = polyHeight
+	i.fromChainID = fromChainID
+	copy(i.fromTx[:], fromTx)
+	return nil
 }
@@ -317,6 +317,51 @@ func ecrecoverCommitter(header *types.Header, committedSeal []byte) (pltcm.Addre
 	return istanbul.GetSignatureAddress(proposalSeal, committedSeal)
 }
 
+// verifyPaletteHeader check that the header is proposed and committed by validators in `valset`, which is
+// the validators recorded in its parent block, and the quorum of them should have committed the header.
+func verifyPaletteHeader(header *types.Header, valset []pltcm.Address) error {
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return fmt.Errorf("extract istanbul extra err: %s", err)
+	}
+
+	isValidator := make(map[pltcm.Address]bool)
+	for _, v := range valset {
+		isValidator[v] = true
+	}
+
+	proposer, err := ecrecoverProposer(header, extra)
+	if err != nil {
+		return fmt.Errorf("recover proposer err: %s", err)
+	}
+	if !isValidator[proposer] {
+		return fmt.Errorf("proposer %s is not validator", proposer.Hex())
+	}
+
+	committers := make(map[pltcm.Address]bool)
+	for _, seal := range extra.CommittedSeal {
+		committer, err := ecrecoverCommitter(header, seal)
+		if err != nil {
+			return fmt.Errorf("recover committer err: %s", err)
+		}
+		if !isValidator[committer] {
+			return fmt.Errorf("committer %s is not validator", committer.Hex())
+		}
+		committers[committer] = true
+	}
+	if quorum := quorumSize(len(valset)); len(committers) < quorum {
+		return fmt.Errorf("committed seals not enough, %d of %d validators committed, quorum is %d",
+			len(committers), len(valset), quorum)
+	}
+	return nil
+}
+
+// quorumSize return the number of committed seals which finalize palette block with `n` validators,
+// istanbul requires ceil(2n/3) seals.
+func quorumSize(n int) int {
+	return (2*n + 2) / 3
+}
+
 func sigHash(header *types.Header) (hash pltcm.Hash) {
 	hasher := sha3.NewLegacyKeccak256()
 
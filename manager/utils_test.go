@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	pltcm "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPaletteHeader(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	valset := make([]pltcm.Address, 4)
+	for i := range keys {
+		keys[i] = fake.Key(uint64(fakeValidatorSeed + i))
+		valset[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	forged := fake.Key(uint64(fakeValidatorSeed + 100))
+
+	cases := []struct {
+		name   string
+		seal   func(committers []*ecdsa.PrivateKey) []*ecdsa.PrivateKey
+		valset []pltcm.Address
+		valid  bool
+	}{
+		{"all committed", nil, valset, true},
+		{"quorum committed", func(c []*ecdsa.PrivateKey) []*ecdsa.PrivateKey { return c[:3] }, valset, true},
+		{"duplicated seals", func(c []*ecdsa.PrivateKey) []*ecdsa.PrivateKey { return append(c[:2], c[0]) }, valset, false},
+		{"quorum not reached", func(c []*ecdsa.PrivateKey) []*ecdsa.PrivateKey { return c[:2] }, valset, false},
+		{"forged committer", func(c []*ecdsa.PrivateKey) []*ecdsa.PrivateKey { return append(c[:3], forged) }, valset, false},
+		{"unknown validators", nil, valset[1:], false},
+	}
+
+	for _, v := range cases {
+		chain := fake.NewPaletteChain(fakeSideChainID, keys)
+		if v.seal != nil {
+			seal := v.seal
+			chain.SealHook = func(_ uint64, committers []*ecdsa.PrivateKey) []*ecdsa.PrivateKey {
+				return seal(committers)
+			}
+		}
+		height := chain.Mine(1)
+		hdr, err := chain.HeaderByNumber(context.Background(), uint64ToBig(height))
+		require.NoError(t, err)
+
+		err = verifyPaletteHeader(hdr, v.valset)
+		if v.valid {
+			assert.NoError(t, err, v.name)
+		} else {
+			assert.Error(t, err, v.name)
+		}
+	}
+
+	// quorum of 5 and 6 validators is 2F+1 seals, which is not more than 2/3 of validators.
+	quorumCases := []struct {
+		validators, quorum int
+	}{
+		{1, 1}, {4, 3}, {5, 4}, {6, 4}, {7, 5}, {10, 7},
+	}
+
+	for _, v := range quorumCases {
+		assert.Equal(t, v.quorum, quorumSize(v.validators), "%d validators", v.validators)
+		if v.validators < 5 || v.validators > 6 {
+			continue
+		}
+
+		keys := make([]*ecdsa.PrivateKey, v.validators)
+		valset := make([]pltcm.Address, v.validators)
+		for i := range keys {
+			keys[i] = fake.Key(uint64(fakeValidatorSeed + i))
+			valset[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		}
+		for seals := v.quorum - 1; seals <= v.quorum+1; seals++ {
+			num := seals
+			chain := fake.NewPaletteChain(fakeSideChainID, keys)
+			chain.SealHook = func(_ uint64, committers []*ecdsa.PrivateKey) []*ecdsa.PrivateKey {
+				return committers[:num]
+			}
+			hdr, err := chain.HeaderByNumber(context.Background(), uint64ToBig(chain.Mine(1)))
+			require.NoError(t, err)
+
+			err = verifyPaletteHeader(hdr, valset)
+			if seals >= v.quorum {
+				assert.NoError(t, err, "%d seals of %d validators", seals, v.validators)
+			} else {
+				assert.Error(t, err, "%d seals of %d validators", seals, v.validators)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"fmt"
+
+	pltcm "github.com/ethereum/go-ethereum/common"
+	plttyp "github.com/ethereum/go-ethereum/core/types"
+	"github.com/palettechain/palette-relayer/db"
+	"github.com/palettechain/palette-relayer/log"
+	polycm "github.com/polynetwork/poly/common"
+)
+
+// recordValSet persist the validators of epoch header in history, so that operators are able to audit
+// when and how palette validators changed, and the epoch is not lost if relayer restarts before the
+// header synced to poly chain.
+func (m *PaletteManager) recordValSet(hdr *plttyp.Header, epoch *pltEpoch) {
+	vals := make([]pltcm.Address, len(epoch.valset))
+	copy(vals, epoch.valset)
+	sortAddrList(vals)
+
+	record := &ValSetRecord{
+		Height:     epoch.height,
+		Hash:       hdr.Hash(),
+		Validators: vals,
+		raw:        epoch.raw,
+	}
+	sink := polycm.NewZeroCopySink(nil)
+	record.Serialization(sink)
+	if err := m.db.PutValSet(epoch.height, sink.Bytes()); err != nil {
+		log.Errorf("PaletteManager recordValSet - m.db.PutValSet on height %d error: %s", epoch.height, err)
+	}
+}
+
+// restoreValSet requeue epoch headers which are recorded in history but not synced to poly chain yet.
+func (m *PaletteManager) restoreValSet() {
+	records, err := loadValSetFrom(m.db, m.latestEpoch().height+1)
+	if err != nil {
+		log.Errorf("PaletteManager restoreValSet - load validators history err: %s", err)
+		return
+	}
+
+	for _, r := range records {
+		m.pendingHeaders = append(m.pendingHeaders, &pltEpoch{
+			height: r.Height,
+			raw:    r.raw,
+			valset: r.Validators,
+		})
+		log.Infof("PaletteManager restoreValSet - requeue epoch header on height %d, valset size %d",
+			r.Height, len(r.Validators))
+	}
+}
+
+// ListValSetHistory return all of palette validators recorded by relayer in ascending order of height.
+func ListValSetHistory(boltDB *db.BoltDB) ([]*ValSetRecord, error) {
+	return loadValSetFrom(boltDB, 0)
+}
+
+func loadValSetFrom(boltDB *db.BoltDB, height uint64) ([]*ValSetRecord, error) {
+	heights, list, err := boltDB.GetValSetFrom(height)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*ValSetRecord, 0, len(list))
+	for i, v := range list {
+		record := new(ValSetRecord)
+		if err := record.Deserialization(polycm.NewZeroCopySource(v)); err != nil {
+			return nil, fmt.Errorf("deserialize validators on height %d err: %s", heights[i], err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
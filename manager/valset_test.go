@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	pltcm "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakePaletteValSetHistory(t *testing.T) {
+	env := newFakeEnv(t)
+	mgr := env.paletteManager(t)
+
+	epochs := []uint64{env.changeValidators(0x10), env.changeValidators(0x11)}
+	syncPalette(t, mgr)
+
+	history, err := ListValSetHistory(env.db)
+	require.NoError(t, err)
+	require.Equal(t, len(epochs), len(history))
+	for i, v := range history {
+		hdr, err := env.palette.HeaderByNumber(context.Background(), uint64ToBig(epochs[i]))
+		require.NoError(t, err)
+		assert.Equal(t, epochs[i], v.Height)
+		assert.Equal(t, hdr.Hash(), v.Hash)
+		assert.Equal(t, len(env.valset)-len(epochs)+i+1, len(v.Validators))
+		for j := 1; j < len(v.Validators); j++ {
+			assert.True(t, v.Validators[j-1].Hex() < v.Validators[j].Hex())
+		}
+	}
+	vals := make([]pltcm.Address, len(env.valset))
+	copy(vals, env.valset)
+	sortAddrList(vals)
+	assert.Equal(t, vals, history[1].Validators)
+
+	// validators of orphaned blocks are dropped.
+	mgr.rollback(epochs[1] - 1)
+	history, err = ListValSetHistory(env.db)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(history))
+	assert.Equal(t, epochs[0], history[0].Height)
+}
+
+func TestFakePaletteValSetRestored(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.HeadersPerBatch = 2
+	mgr := env.paletteManager(t)
+
+	epochHeight := env.changeValidators(0x10)
+	height, err := env.palette.GetNodeHeight()
+	require.NoError(t, err)
+	require.True(t, mgr.handleBlockRange(context.Background(), height))
+	require.Equal(t, 1, len(mgr.pendingHeaders))
+	require.Nil(t, mgr.inflight)
+
+	// restart relayer before the epoch header committed.
+	restarted := env.paletteManager(t)
+	require.Equal(t, 1, len(restarted.pendingHeaders))
+	assert.Equal(t, epochHeight, restarted.pendingHeaders[0].height)
+	assert.Equal(t, mgr.pendingHeaders[0].raw, restarted.pendingHeaders[0].raw)
+
+	syncPalette(t, restarted)
+	synced := env.poly.SyncedHeaders()
+	require.Equal(t, 1, len(synced))
+	assert.Equal(t, epochHeight, restarted.lastEpoch.height)
+	assert.Equal(t, len(env.valset), len(restarted.lastEpoch.valset))
+}
@@ -0,0 +1,279 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package manager
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/palettechain/palette-relayer/config"
+	"github.com/palettechain/palette-relayer/log"
+)
+
+// defaultRoutineNum is used if `ServiceConfig.RoutineNum` is not configured.
+const defaultRoutineNum = 1
+
+// SenderStats is the load of palette sender's worker pool.
+type SenderStats struct {
+	Address  string
+	Workers  int
+	Pending  int64
+	Queued   int
+	QueueCap int
+	// Blocked is the number of transactions which waited for the full queue.
+	Blocked uint64
+}
+
+// txSet is the set of keys of palette transactions which are queued or being sent by senders, it's shared
+// by senders so that the same poly transaction is never relayed twice at the same time.
+type txSet struct {
+	mtx  sync.Mutex
+	keys map[string]struct{}
+}
+
+func newTxSet() *txSet {
+	return &txSet{keys: make(map[string]struct{})}
+}
+
+// add put the key into set, and return false if it's already in.
+func (t *txSet) add(key []byte) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if _, ok := t.keys[string(key)]; ok {
+		return false
+	}
+	t.keys[string(key)] = struct{}{}
+	return true
+}
+
+func (t *txSet) remove(key []byte) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	delete(t.keys, string(key))
+}
+
+// validateWorkers check the number of workers and queue length of palette senders.
+func validateWorkers(cfg *config.ServiceConfig) error {
+	if cfg.RoutineNum < 0 {
+		return fmt.Errorf("invalid RoutineNum %d, it should not be negative", cfg.RoutineNum)
+	}
+	if cfg.QueueLength < 0 {
+		return fmt.Errorf("invalid QueueLength %d, it should not be negative", cfg.QueueLength)
+	}
+	return nil
+}
+
+// startWorkers start `num` workers consuming the queue of sender. the transaction is deleted from db after
+// it's confirmed or found relayed already, and moved to `palette dead letter` bucket if it's reverted. otherwise
+// it's resent after `PaletteConfig.RetryInterval`, and kept for replaying if sending is aborted.
+func (s *PaletteSender) startWorkers(num int) {
+	s.wg.Add(num)
+	for i := 0; i < num; i++ {
+		go func() {
+			defer s.wg.Done()
+			for v := range s.queue {
+				atomic.AddInt64(&s.pending, -1)
+				s.relayTx(v)
+			}
+		}()
+	}
+}
+
+// relayTx send the persisted transaction until it's done or sending is aborted. the sender is picked again
+// by selector once the current one is unhealthy or fails `PaletteConfig.MaxSendFailures` times, so that
+// the transaction is not stuck with the account which is out of balance or rejected by palette node.
+// the transaction not confirmed before deadline is sent again with bumped gas price, and it's kept for
+// replaying after restart once the gas price reaches `PaletteConfig.MaxGasPrice`.
+func (s *PaletteSender) relayTx(v *PaletteTxInfo) {
+	defer s.inflight.remove(v.key())
+
+	var minGasPrice *big.Int
+	sender, failures := s, 0
+	for {
+		if s.ctx.Err() != nil {
+			log.Errorf("PolyManager - drop tx of poly_tx %s, sending aborted: %v, it will be replayed "+
+				"after restart", v.polyTxHash, s.ctx.Err())
+			return
+		}
+		if failures >= s.maxSendFailures() || !sender.healthy() {
+			sender, failures = sender.reselect(v), 0
+		}
+		lastGasPrice, err := sender.sendTxToPalette(v.contractAddr, v.polyTxHash, v.txData, minGasPrice)
+		if err != nil {
+			log.Errorf("PolyManager - failed to send tx to ethereum: error: %v, txData: %s",
+				err, hex.EncodeToString(v.txData))
+		}
+		if s.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrTxConfirmTimeout) {
+			bumped, ok := sender.bumpGasPrice(lastGasPrice)
+			if !ok {
+				log.Errorf("PolyManager relayTx - tx of poly_tx %s is not confirmed, but gas price %s reaches "+
+					"the ceiling, it will be replayed after restart", v.polyTxHash, lastGasPrice)
+				return
+			}
+			log.Warnf("PolyManager relayTx - tx of poly_tx %s is not confirmed, resend it with gas price %s",
+				v.polyTxHash, bumped)
+			minGasPrice = bumped
+			continue
+		}
+		if s.settleTx(v, err) {
+			return
+		}
+		failures++
+		log.Warnf("PolyManager relayTx - resend tx of poly_tx %s after %s", v.polyTxHash, s.resendInterval())
+		select {
+		case <-time.After(s.resendInterval()):
+		case <-s.ctx.Done():
+		}
+	}
+}
+
+// settleTx remove the persisted transaction from db once it's confirmed or executed on palette chain, and
+// move it to `palette dead letter` bucket if it's reverted but not executed, since sending it again won't help.
+// it returns false if the transaction should be sent again.
+func (s *PaletteSender) settleTx(v *PaletteTxInfo, err error) bool {
+	if err != nil {
+		relayed, checkErr := s.eccd.CheckIfFromChainTxExist(nil, v.fromChainID, v.fromTx)
+		if checkErr != nil {
+			log.Errorf("PolyManager settleTx - check poly_tx %s on palette chain error: %v", v.polyTxHash, checkErr)
+			return false
+		}
+		if !relayed {
+			if !isRevertError(err) {
+				return false
+			}
+			if err := s.db.MovePaletteTxToDeadLetter(v.key()); err != nil {
+				log.Errorf("PolyManager settleTx - failed to move tx of poly_tx %s to dead letter: %v",
+					v.polyTxHash, err)
+			}
+			log.Errorf("PolyManager settleTx - tx of poly_tx %s is reverted, moved to dead letter, err: %v",
+				v.polyTxHash, err)
+			return true
+		}
+		log.Infof("PolyManager settleTx - poly_tx %s already relayed", v.polyTxHash)
+	}
+
+	if err := s.db.DeletePaletteTx(v.key()); err != nil {
+		log.Errorf("PolyManager - failed to delete tx of poly_tx %s: %v", v.polyTxHash, err)
+	}
+	return true
+}
+
+// reselect pick another available sender for the transaction, and the current sender is kept if none
+// of the others is available.
+func (s *PaletteSender) reselect(v *PaletteTxInfo) *PaletteSender {
+	next := s.selectSender()
+	if next == nil || next == s {
+		return s
+	}
+	log.Warnf("PolyManager reselect - sender %s is unavailable, tx of poly_tx %s is handed over to sender %s",
+		s.acc.Address.Hex(), v.polyTxHash, next.acc.Address.Hex())
+	return next
+}
+
+// isRevertError return true if the transaction is reverted on chain, or the call is reverted while
+// estimating gas.
+func isRevertError(err error) bool {
+	return errors.Is(err, ErrTxReverted) || strings.Contains(strings.ToLower(err.Error()), "execution reverted")
+}
+
+func (s *PaletteSender) resendInterval() time.Duration {
+	if s.config.PaletteConfig.RetryInterval <= 0 {
+		return defaultRetryInterval
+	}
+	return time.Duration(s.config.PaletteConfig.RetryInterval) * time.Second
+}
+
+// enqueue push the persisted palette transaction into the queue of sender, and it blocks until workers
+// catch up if the queue is full. the transaction is skipped if it's already queued or being sent, which
+// happens if the poly height is handled again after restart or failure.
+func (s *PaletteSender) enqueue(info *PaletteTxInfo) {
+	if !s.inflight.add(info.key()) {
+		log.Infof("PolyManager enqueue - poly_tx %s is already queued", info.polyTxHash)
+		return
+	}
+	atomic.AddInt64(&s.pending, 1)
+	select {
+	case s.queue <- info:
+		return
+	default:
+	}
+
+	blocked := atomic.AddUint64(&s.blocked, 1)
+	log.Warnf("PolyManager enqueue - queue of sender %s is full, %d txs queued, poly_tx %s is waiting, "+
+		"%d txs blocked so far", s.acc.Address.Hex(), cap(s.queue), info.polyTxHash, blocked)
+	start := time.Now()
+	s.queue <- info
+	log.Warnf("PolyManager enqueue - poly_tx %s queued after waiting %s", info.polyTxHash, time.Since(start))
+}
+
+// stop close the queue and wait until workers exit.
+func (s *PaletteSender) stop() {
+	close(s.queue)
+	s.wg.Wait()
+}
+
+// SenderStats return the load of palette senders' worker pools.
+func (m *PolyManager) SenderStats() []*SenderStats {
+	list := make([]*SenderStats, 0, len(m.senders))
+	for _, s := range m.senders {
+		list = append(list, &SenderStats{
+			Address:  s.acc.Address.Hex(),
+			Workers:  m.workerNum(),
+			Pending:  s.pendingTxs(),
+			Queued:   len(s.queue),
+			QueueCap: cap(s.queue),
+			Blocked:  atomic.LoadUint64(&s.blocked),
+		})
+	}
+	return list
+}
+
+// logSenderStats report the load of senders which have transactions waiting or ever blocked by the full queue.
+func (m *PolyManager) logSenderStats() {
+	for _, v := range m.SenderStats() {
+		if v.Pending == 0 && v.Blocked == 0 {
+			continue
+		}
+		log.Infof("PolyManager logSenderStats - sender %s: %d workers, %d txs pending, %d/%d queued, "+
+			"%d txs blocked by full queue", v.Address, v.Workers, v.Pending, v.Queued, v.QueueCap, v.Blocked)
+	}
+}
+
+func (m *PolyManager) workerNum() int {
+	if m.config.RoutineNum <= 0 {
+		return defaultRoutineNum
+	}
+	return int(m.config.RoutineNum)
+}
+
+func (m *PolyManager) queueLength() int {
+	if m.config.QueueLength <= 0 {
+		return ChanLen
+	}
+	return m.config.QueueLength
+}
@@ -0,0 +1,223 @@
+package manager
+
+import (
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	pltcm "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/palettechain/palette-relayer/manager/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakePolyManagerWorkersConfig(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.RoutineNum = -1
+	_, err := newPolyManager(env.cfg, 0, env.poly, env.palette, env.palette, env.signer, env.signer.Accounts(), env.db)
+	assert.Error(t, err)
+
+	env.cfg.RoutineNum = 0
+	env.cfg.QueueLength = -1
+	_, err = newPolyManager(env.cfg, 0, env.poly, env.palette, env.palette, env.signer, env.signer.Accounts(), env.db)
+	assert.Error(t, err)
+
+	// one worker and default queue length are used if they are not configured.
+	env.cfg.QueueLength = 0
+	mgr := env.polyManager(t)
+	stats := mgr.SenderStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Workers)
+	assert.Equal(t, ChanLen, stats[0].QueueCap)
+
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	mgr.Stop()
+	assert.Equal(t, 1, len(env.palette.Transactions()))
+}
+
+func TestFakePolyManagerWorkersOrdered(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.RoutineNum = 4
+	mgr := env.polyManager(t)
+
+	num := 8
+	for i := 0; i < num; i++ {
+		env.emitPolyDeposit(uint32(10+i), byte(i+1))
+		require.True(t, mgr.handleDepositEvents(uint32(10+i)))
+	}
+	waitMined(t, env, num)
+	mgr.Stop()
+
+	// nonces are consumed in the order they are broadcast, so that none of transactions is blocked by gaps.
+	for i, tx := range env.palette.Transactions() {
+		assert.Equal(t, uint64(i), tx.Nonce())
+	}
+	assert.Empty(t, mgr.senders[0].nonceManager.Gaps(mgr.senders[0].acc.Address))
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
+
+func TestFakePolyManagerWorkersBackpressure(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.QueueLength = 1
+	mgr := env.polyManager(t)
+	sender := mgr.senders[0]
+
+	// skip a nonce, so that the only worker waits for the first transaction.
+	gap := sender.nonceManager.UseNonce(sender.acc.Address)
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	assert.Eventually(t, func() bool {
+		return mgr.SenderStats()[0].Pending == 0
+	}, 5*time.Second, 20*time.Millisecond)
+	env.emitPolyDeposit(11, 2)
+	require.True(t, mgr.handleDepositEvents(11))
+	assert.Equal(t, 1, mgr.SenderStats()[0].Queued)
+
+	// the third transaction waits for the full queue.
+	env.emitPolyDeposit(12, 3)
+	done := make(chan bool)
+	go func() { done <- mgr.handleDepositEvents(12) }()
+	assert.Eventually(t, func() bool {
+		return mgr.SenderStats()[0].Blocked == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, int64(2), mgr.SenderStats()[0].Pending)
+
+	// workers catch up once the gap is filled.
+	raw := types.NewTransaction(gap, sender.acc.Address, big.NewInt(0), fillerGasLimit, big.NewInt(0), nil)
+	tx, err := sender.keyStore.SignTransaction(raw, sender.acc)
+	require.NoError(t, err)
+	require.NoError(t, env.palette.SendTransaction(sender.ctx, tx, bind.PrivateTxArgs{}))
+	assert.True(t, <-done)
+	waitMined(t, env, 4)
+	mgr.Stop()
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
+
+func TestFakePolyManagerWorkersResend(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.PaletteConfig.RetryInterval = 1
+	mgr := env.polyManager(t)
+
+	// the node is unreachable for the first broadcast.
+	var sent int32
+	env.palette.SendTxHook = func(_ *types.Transaction) error {
+		if atomic.AddInt32(&sent, 1) == 1 {
+			return errors.New("dial tcp 127.0.0.1:8545: connect: connection refused")
+		}
+		return nil
+	}
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&sent) == 1 && countPaletteTxs(t, env.db) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// the persisted transaction is kept and resent after retry interval.
+	waitMined(t, env, 1)
+	mgr.Stop()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&sent))
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
+
+func TestFakePolyManagerWorkersReverted(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	mgr := env.polyManager(t)
+
+	// the second poly tx is executed by others right before estimating gas.
+	var reverted int32
+	env.palette.EstimateGasHook = func(_ ethereum.CallMsg) error {
+		if atomic.AddInt32(&reverted, 1) == 2 {
+			env.palette.MarkRelayed(fakeToChainID, pltcm.BytesToHash([]byte{2}))
+		}
+		return errors.New("execution reverted: verify header failed")
+	}
+
+	// the reverted transaction is kept in dead letter unless it's executed on palette chain.
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	env.emitPolyDeposit(11, 2)
+	require.True(t, mgr.handleDepositEvents(11))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&reverted) == 2 && countPaletteTxs(t, env.db) == 0
+	}, 5*time.Second, 20*time.Millisecond)
+	mgr.Stop()
+
+	backlog, err := GetBacklog(env.db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, backlog.PaletteDeadLetter)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&reverted))
+	assert.Empty(t, env.palette.Transactions())
+}
+
+func TestFakePolyManagerWorkersReselect(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.PaletteConfig.RetryInterval = 1
+	env.cfg.PaletteConfig.MaxSendFailures = 2
+	env.cfg.PaletteConfig.SenderStrategy = SenderRoundRobin
+	signer := fake.NewSigner(fakeSideChainID, fake.Key(1), fake.Key(2))
+	mgr, err := newPolyManager(env.cfg, 0, env.poly, env.palette, env.palette, signer, signer.Accounts(), env.db)
+	require.NoError(t, err)
+
+	// the first sender is always rejected by palette node.
+	broken := mgr.senders[0].acc.Address
+	var rejected int32
+	env.palette.SendTxHook = func(tx *types.Transaction) error {
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+		if err != nil || from != broken {
+			return err
+		}
+		atomic.AddInt32(&rejected, 1)
+		return errors.New("insufficient funds for gas * price + value")
+	}
+
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	waitMined(t, env, 1)
+	mgr.Stop()
+
+	from, err := types.Sender(types.NewEIP155Signer(env.palette.Transactions()[0].ChainId()), env.palette.Transactions()[0])
+	require.NoError(t, err)
+	assert.Equal(t, mgr.senders[1].acc.Address, from)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&rejected))
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
+
+func TestFakePolyManagerWorkersConfirmTimeout(t *testing.T) {
+	env := newFakeEnv(t)
+	env.cfg.PaletteConfig.TxPollInterval = 50
+	env.cfg.PaletteConfig.ConfirmTimeout = 1
+	env.cfg.PaletteConfig.GasPrice = 10
+	env.cfg.PaletteConfig.GasPriceBump = 10
+	env.palette.MinGasPrice = big.NewInt(12)
+	mgr := env.polyManager(t)
+
+	sent := make([]*types.Transaction, 0)
+	env.palette.SendTxHook = func(tx *types.Transaction) error {
+		sent = append(sent, tx)
+		return nil
+	}
+
+	// the transaction timed out is sent again with the same nonce and bumped gas price until it's mined.
+	env.emitPolyDeposit(10, 1)
+	require.True(t, mgr.handleDepositEvents(10))
+	waitMined(t, env, 1)
+	mgr.Stop()
+
+	require.Equal(t, 3, len(sent))
+	for i, expect := range []int64{10, 11, 12} {
+		assert.Equal(t, big.NewInt(expect), sent[i].GasPrice())
+		assert.Equal(t, uint64(0), sent[i].Nonce())
+	}
+	assert.Equal(t, 0, countPaletteTxs(t, env.db))
+}
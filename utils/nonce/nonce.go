@@ -18,71 +18,369 @@ package nonce
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	pltcli "github.com/ethereum/go-ethereum/ethclient"
 	"github.com/palettechain/palette-relayer/log"
+	polycm "github.com/polynetwork/poly/common"
 )
 
+// Client fetch account's pending nonce on chain, it is satisfied by palette client.
+type Client interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// Store persist the nonce state of accounts, it is satisfied by bolt db.
+type Store interface {
+	PutNonce(account []byte, v []byte) error
+	GetNonce(account []byte) []byte
+}
+
+// nonceErrors is the error messages returned by palette node if the nonce of transaction mismatches the account.
+var nonceErrors = []string{
+	"nonce too low",
+	"nonce too high",
+	"invalid nonce",
+	"replacement transaction underpriced",
+}
+
+// IsNonceError return true if the transaction is rejected because of its nonce, which means the nonce
+// manager is out of sync with palette chain.
+func IsNonceError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, v := range nonceErrors {
+		if strings.Contains(msg, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// accountNonce is the nonce state of account. nonces lower than `next` are either broadcast, which are
+// kept in `inflight` until confirmed, or returned, which are kept in `backup` for reusing. `returned` is
+// the time when nonces in `backup` were returned, it's kept in memory only.
+type accountNonce struct {
+	next     uint64
+	inflight map[uint64]struct{}
+	backup   []uint64
+	returned map[uint64]time.Time
+}
+
+func newAccountNonce(next uint64) *accountNonce {
+	return &accountNonce{
+		next:     next,
+		inflight: make(map[uint64]struct{}),
+		returned: make(map[uint64]time.Time),
+	}
+}
+
+// addBackup insert the returned nonce into `backup` in ascending order.
+func (a *accountNonce) addBackup(nonce uint64) {
+	idx := sort.Search(len(a.backup), func(i int) bool { return a.backup[i] >= nonce })
+	if idx < len(a.backup) && a.backup[idx] == nonce {
+		return
+	}
+	a.backup = append(a.backup, 0)
+	copy(a.backup[idx+1:], a.backup[idx:])
+	a.backup[idx] = nonce
+	a.returned[nonce] = time.Now()
+}
+
+// highestInflight return the highest in-flight nonce, and false if no nonce is in flight.
+func (a *accountNonce) highestInflight() (uint64, bool) {
+	var (
+		highest uint64
+		found   bool
+	)
+	for v := range a.inflight {
+		if !found || v > highest {
+			highest, found = v, true
+		}
+	}
+	return highest, found
+}
+
+func (a *accountNonce) Serialization(sink *polycm.ZeroCopySink) {
+	inflight := make([]uint64, 0, len(a.inflight))
+	for v := range a.inflight {
+		inflight = append(inflight, v)
+	}
+	sort.Slice(inflight, func(i, j int) bool { return inflight[i] < inflight[j] })
+
+	sink.WriteUint64(a.next)
+	for _, list := range [][]uint64{inflight, a.backup} {
+		sink.WriteVarUint(uint64(len(list)))
+		for _, v := range list {
+			sink.WriteUint64(v)
+		}
+	}
+}
+
+func (a *accountNonce) Deserialization(source *polycm.ZeroCopySource) error {
+	var eof bool
+	if a.next, eof = source.NextUint64(); eof {
+		return fmt.Errorf("read next nonce eof")
+	}
+
+	lists := make([][]uint64, 2)
+	for i := range lists {
+		num, eof := source.NextVarUint()
+		if eof {
+			return fmt.Errorf("read length of nonce list eof")
+		}
+		if num > source.Len()/8 {
+			return fmt.Errorf("length of nonce list %d exceeds the remaining data", num)
+		}
+		lists[i] = make([]uint64, num)
+		for j := range lists[i] {
+			if lists[i][j], eof = source.NextUint64(); eof {
+				return fmt.Errorf("read nonce eof")
+			}
+		}
+	}
+
+	a.inflight = make(map[uint64]struct{}, len(lists[0]))
+	for _, v := range lists[0] {
+		a.inflight[v] = struct{}{}
+	}
+	a.backup = lists[1]
+	a.returned = make(map[uint64]time.Time, len(a.backup))
+	for _, v := range a.backup {
+		a.returned[v] = time.Now()
+	}
+	return nil
+}
+
+// NonceManager allocate nonces of accounts. the state of each account is persisted in `store`, and it is
+// synced with palette chain once loaded and whenever palette node rejects a nonce.
 type NonceManager struct {
-	online map[common.Address]uint64   // the nonce obtained on chain stored in `online` map first.
-	backup map[common.Address][]uint64 // if nonce is bigger than current nonce, the value will append in the `backup` list for future usage.
-	client *pltcli.Client
-	mtx    *sync.Mutex
+	accounts map[common.Address]*accountNonce
+	client   Client
+	store    Store
+	mtx      *sync.Mutex
 }
 
-func NewNonceManager(ethClient *pltcli.Client) *NonceManager {
+// NewNonceManager create nonce manager, and the nonce state is kept in memory only if `store` is nil.
+func NewNonceManager(ethClient Client, store Store) *NonceManager {
 	return &NonceManager{
-		online: make(map[common.Address]uint64),
-		backup: make(map[common.Address][]uint64),
-		client: ethClient,
-		mtx:    new(sync.Mutex),
+		accounts: make(map[common.Address]*accountNonce),
+		client:   ethClient,
+		store:    store,
+		mtx:      new(sync.Mutex),
 	}
 }
 
-// UseNonce get nonce on chain if it not exist in `backup` list.
+// UseNonce get the minimum returned nonce of account, or allocate a new one if none was returned.
+// the nonce is in flight until it's confirmed or returned.
 func (m *NonceManager) UseNonce(address common.Address) uint64 {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
+	acc := m.load(address)
+
 	// get minimum nonce which located at the head of `backup` list.
 	// and right shift the `backup` list for next usage.
-	if len(m.backup[address]) > 0 {
-		nonce := m.backup[address][0]
-		m.backup[address] = m.backup[address][1:]
-		return nonce
-	}
-
-	// get nonce on chain and set it in `online` map
-	nonce, ok := m.online[address]
-	if !ok {
-		uintNonce, err := m.client.PendingNonceAt(context.Background(), address)
-		if err != nil {
-			log.Errorf("failed to get %s nonce, err: %s, set it to 0!", address, err)
-		}
-		m.online[address] = uintNonce
-		nonce = uintNonce
+	var nonce uint64
+	if len(acc.backup) > 0 {
+		nonce = acc.backup[0]
+		acc.backup = acc.backup[1:]
+		delete(acc.returned, nonce)
+	} else {
+		nonce = acc.next
+		acc.next++
 	}
+	acc.inflight[nonce] = struct{}{}
 
-	// increase record
-	m.online[address]++
+	m.save(address, acc)
 	return nonce
 }
 
+// ReturnNonce give back the nonce of transaction which is not broadcast or not mined before deadline,
+// so that it is reused by the next transaction of account.
 func (m *NonceManager) ReturnNonce(addr common.Address, nonce uint64) {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
-	list, ok := m.backup[addr]
-	if !ok {
-		list = make([]uint64, 0)
+	acc := m.load(addr)
+	delete(acc.inflight, nonce)
+	if nonce < acc.next {
+		acc.addBackup(nonce)
 	}
-	list = append(list, nonce)
+	m.save(addr, acc)
+}
+
+// ConfirmNonce release the nonce of transaction which is mined on palette chain.
+func (m *NonceManager) ConfirmNonce(addr common.Address, nonce uint64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
 
-	sort.Slice(list, func(i, j int) bool {
-		return list[i] < list[j]
-	})
-	m.backup[addr] = list
+	acc := m.load(addr)
+	if _, ok := acc.inflight[nonce]; !ok {
+		return
+	}
+	delete(acc.inflight, nonce)
+	m.save(addr, acc)
+}
+
+// Resync sync the nonce state of account with palette chain, it should be called once palette node rejects
+// a transaction because of its nonce.
+func (m *NonceManager) Resync(addr common.Address) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	acc := m.load(addr)
+	if m.sync(addr, acc, false) {
+		m.save(addr, acc)
+	}
+}
+
+// Gaps return returned nonces of account which are lower than any in-flight nonce, transactions with
+// higher nonces are blocked on palette chain until these nonces are used.
+func (m *NonceManager) Gaps(addr common.Address) []uint64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	acc := m.load(addr)
+	highest, ok := acc.highestInflight()
+	gaps := make([]uint64, 0)
+	for _, v := range acc.backup {
+		if !ok || v >= highest {
+			break
+		}
+		gaps = append(gaps, v)
+	}
+	return gaps
+}
+
+// TakeGaps mark gaps of account which were returned longer than `age` in flight, and return them
+// so that the caller fills them. nonces which are not used by the caller should be returned again.
+func (m *NonceManager) TakeGaps(addr common.Address, age time.Duration) []uint64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	acc := m.load(addr)
+	highest, ok := acc.highestInflight()
+	taken := make([]uint64, 0)
+	backup := make([]uint64, 0, len(acc.backup))
+	for _, v := range acc.backup {
+		if ok && v < highest && time.Since(acc.returned[v]) >= age {
+			taken = append(taken, v)
+			acc.inflight[v] = struct{}{}
+			delete(acc.returned, v)
+			continue
+		}
+		backup = append(backup, v)
+	}
+	if len(taken) == 0 {
+		return taken
+	}
+	acc.backup = backup
+	m.save(addr, acc)
+	return taken
+}
+
+// load get the nonce state of account from memory, or from store and sync it with palette chain if it's
+// not loaded yet.
+func (m *NonceManager) load(addr common.Address) *accountNonce {
+	if acc, ok := m.accounts[addr]; ok {
+		return acc
+	}
+
+	var raw []byte
+	if m.store != nil {
+		raw = m.store.GetNonce(addr.Bytes())
+	}
+	acc := newAccountNonce(0)
+	if raw != nil {
+		if err := acc.Deserialization(polycm.NewZeroCopySource(raw)); err != nil {
+			log.Errorf("NonceManager load - deserialize nonce state of %s err: %s", addr.Hex(), err)
+			acc, raw = newAccountNonce(0), nil
+		}
+	}
+	m.accounts[addr] = acc
+
+	// account without nonce state starts from the pending nonce on chain.
+	if raw == nil {
+		nonce, err := m.client.PendingNonceAt(context.Background(), addr)
+		if err != nil {
+			log.Errorf("NonceManager load - failed to get %s nonce, err: %s, set it to 0!", addr.Hex(), err)
+		}
+		acc.next = nonce
+		m.save(addr, acc)
+	} else if m.sync(addr, acc, true) {
+		m.save(addr, acc)
+	}
+	return acc
+}
+
+// sync drop nonces which are already used on palette chain, and skip nonces used by other clients of
+// the same account. nonces between the chain's pending nonce and `next` which are neither in flight nor
+// returned are lost, they are returned so that the gap will be filled. once `restarted`, the in-flight
+// pending nonce is lost as well, because it was never broadcast before last shutdown, otherwise palette
+// node would count it. it returns true if state changed.
+func (m *NonceManager) sync(addr common.Address, acc *accountNonce, restarted bool) bool {
+	pending, err := m.client.PendingNonceAt(context.Background(), addr)
+	if err != nil {
+		log.Errorf("NonceManager sync - failed to get %s nonce, err: %s", addr.Hex(), err)
+		return false
+	}
+
+	changed := false
+	for v := range acc.inflight {
+		if v < pending {
+			delete(acc.inflight, v)
+			changed = true
+		}
+	}
+	backup := make([]uint64, 0, len(acc.backup))
+	for _, v := range acc.backup {
+		if v >= pending {
+			backup = append(backup, v)
+		} else {
+			delete(acc.returned, v)
+		}
+	}
+	if len(backup) != len(acc.backup) {
+		changed = true
+	}
+	acc.backup = backup
+
+	if pending > acc.next {
+		log.Warnf("NonceManager sync - nonce of %s on chain is %d, skip nonces in [%d, %d)",
+			addr.Hex(), pending, acc.next, pending)
+		acc.next = pending
+		return true
+	}
+
+	if _, ok := acc.inflight[pending]; ok && restarted {
+		delete(acc.inflight, pending)
+	}
+	for v := pending; v < acc.next; v++ {
+		_, inflight := acc.inflight[v]
+		if _, ok := acc.returned[v]; ok || inflight {
+			continue
+		}
+		log.Warnf("NonceManager sync - nonce %d of %s is lost, nonce on chain is %d", v, addr.Hex(), pending)
+		acc.addBackup(v)
+		changed = true
+	}
+	return changed
+}
+
+func (m *NonceManager) save(addr common.Address, acc *accountNonce) {
+	if m.store == nil {
+		return
+	}
+	sink := polycm.NewZeroCopySink(nil)
+	acc.Serialization(sink)
+	if err := m.store.PutNonce(addr.Bytes(), sink.Bytes()); err != nil {
+		log.Errorf("NonceManager save - persist nonce state of %s err: %s", addr.Hex(), err)
+	}
 }
@@ -0,0 +1,124 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClient struct {
+	nonce uint64
+}
+
+func (c *testClient) PendingNonceAt(_ context.Context, _ common.Address) (uint64, error) {
+	return c.nonce, nil
+}
+
+type testStore map[string][]byte
+
+func (s testStore) PutNonce(account []byte, v []byte) error {
+	s[string(account)] = v
+	return nil
+}
+
+func (s testStore) GetNonce(account []byte) []byte {
+	return s[string(account)]
+}
+
+func TestNonceManager(t *testing.T) {
+	addr := common.HexToAddress("0x01")
+	client, store := &testClient{nonce: 5}, make(testStore)
+	m := NewNonceManager(client, store)
+
+	assert.Equal(t, uint64(5), m.UseNonce(addr))
+	assert.Equal(t, uint64(6), m.UseNonce(addr))
+	assert.Equal(t, uint64(7), m.UseNonce(addr))
+	m.ReturnNonce(addr, 6)
+	m.ConfirmNonce(addr, 5)
+	client.nonce = 6
+
+	// the returned nonce blocks in-flight nonce 7.
+	assert.Equal(t, []uint64{6}, m.Gaps(addr))
+
+	// returned nonce is reused after restarted.
+	m = NewNonceManager(client, store)
+	assert.Equal(t, []uint64{6}, m.Gaps(addr))
+	assert.Equal(t, uint64(6), m.UseNonce(addr))
+	assert.Empty(t, m.Gaps(addr))
+	assert.Equal(t, uint64(8), m.UseNonce(addr))
+
+	// nonces used by others are skipped after resync.
+	m.ReturnNonce(addr, 8)
+	client.nonce = 20
+	m.Resync(addr)
+	assert.Empty(t, m.Gaps(addr))
+	assert.Equal(t, uint64(20), m.UseNonce(addr))
+}
+
+func TestNonceManagerLostNonces(t *testing.T) {
+	addr := common.HexToAddress("0x01")
+	client, store := &testClient{nonce: 3}, make(testStore)
+	m := NewNonceManager(client, store)
+	for i := 0; i < 6; i++ {
+		m.UseNonce(addr)
+	}
+	m.ConfirmNonce(addr, 4)
+
+	// nonce 3 was never broadcast before restarted, and nonce 4 is confirmed but dropped by palette chain.
+	m = NewNonceManager(client, store)
+	assert.Equal(t, []uint64{3, 4}, m.Gaps(addr))
+	for _, expect := range []uint64{3, 4, 9} {
+		assert.Equal(t, expect, m.UseNonce(addr))
+	}
+
+	// corrupted state is dropped, and account starts from the nonce on chain.
+	store[string(addr.Bytes())] = []byte{0x01}
+	client.nonce = 7
+	m = NewNonceManager(client, store)
+	assert.Equal(t, uint64(7), m.UseNonce(addr))
+}
+
+func TestIsNonceError(t *testing.T) {
+	assert.True(t, IsNonceError(fmt.Errorf("send transaction error: Nonce too low")))
+	assert.True(t, IsNonceError(fmt.Errorf("replacement transaction underpriced")))
+	assert.False(t, IsNonceError(fmt.Errorf("insufficient funds for gas * price + value")))
+	assert.False(t, IsNonceError(nil))
+}
+
+func TestNonceManagerTakeGaps(t *testing.T) {
+	addr := common.HexToAddress("0x01")
+	m := NewNonceManager(&testClient{}, nil)
+	for i := 0; i < 5; i++ {
+		m.UseNonce(addr)
+	}
+	m.ReturnNonce(addr, 1)
+	m.ReturnNonce(addr, 2)
+	m.ReturnNonce(addr, 4)
+
+	// nonce 4 is not a gap, since no higher nonce is in flight.
+	assert.Equal(t, []uint64{1, 2}, m.Gaps(addr))
+	assert.Empty(t, m.TakeGaps(addr, time.Hour))
+	assert.Equal(t, []uint64{1, 2}, m.TakeGaps(addr, 0))
+	assert.Empty(t, m.Gaps(addr))
+	assert.Equal(t, uint64(4), m.UseNonce(addr))
+}